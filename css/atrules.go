@@ -0,0 +1,183 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Import is an @import rule, like `@import url(print.css) supports(display: block) print;`.
+// The rules of the imported stylesheet apply before the ones of the stylesheet importing it,
+// when the supports() condition and the media queries match.
+// See https://www.w3.org/TR/css-cascade-4/#at-import
+type Import struct {
+	URL      string
+	Supports *SupportsCondition // nil without supports()
+	Media    MediaQueryList     // empty for all media
+}
+
+// FontFace is an @font-face rule, which declares the font file of a family, like
+// `@font-face { font-family: Brand; src: url(brand-bold.ttf) format("truetype"); font-weight: bold; }`.
+// See https://www.w3.org/TR/css-fonts-4/#font-face-rule
+type FontFace struct {
+	Family  string
+	Sources []FontSource // the sources of src, the first one available is used
+	Weight  string       // like font-weight: normal, bold or a number
+	Style   string       // like font-style: normal, italic or oblique
+
+	// Conditions are the conditions of the @media and @supports rules containing the font face
+	Conditions
+}
+
+// FontSource is a font file of an @font-face src, like `url(brand.ttf) format("truetype")`,
+// or the name of a font of the system, like `local(Go Mono)`.
+type FontSource struct {
+	URL    string
+	Format string // lowercase format() hint, empty without it
+	Local  string // the font family of local()
+}
+
+// parseImport parses an @import rule, after its name, up to its semicolon.
+func (p *Parser) parseImport() (Import, bool) {
+	imp := Import{}
+	switch {
+	case p.curToken.Type == URL || p.curToken.Type == STRING:
+		imp.URL = p.curToken.Litteral
+		p.nextToken()
+	case isKeyword(p.curToken, "url") && p.peekToken.Type == LPARENTHESIS && !p.peekSpace:
+		f := p.parseFunction()
+		if len(f.Args) != 1 {
+			return p.invalidImport()
+		}
+		imp.URL = f.Args[0].Text
+	}
+	if imp.URL == "" {
+		return p.invalidImport()
+	}
+
+	if isKeyword(p.curToken, "supports") && p.peekToken.Type == LPARENTHESIS && !p.peekSpace {
+		p.nextToken()
+		// supports() holds a condition or a declaration, parsed like the parentheses of a condition
+		condition, ok := p.parseSupportsInParens()
+		if !ok {
+			return p.invalidImport()
+		}
+		imp.Supports = condition
+	}
+
+	imp.Media = p.parseMediaQueryList()
+	switch p.curToken.Type {
+	case SEMICOLON:
+		p.nextToken()
+	case EOF:
+	default:
+		return p.invalidImport()
+	}
+	return imp, true
+}
+
+func (p *Parser) invalidImport() (Import, bool) {
+	p.errors = append(p.errors, "invalid @import")
+	p.skipAtRule()
+	return Import{}, false
+}
+
+// parseFontFace parses the block of descriptors of an @font-face rule.
+// A font face without font-family or src is invalid. Unknown descriptors are ignored.
+func (p *Parser) parseFontFace() (FontFace, bool) {
+	if p.curToken.Type != LBRACE {
+		p.tokenError(LBRACE)
+		p.skipAtRule()
+		return FontFace{}, false
+	}
+	p.nextToken()
+
+	face := FontFace{Weight: "normal", Style: "normal"}
+	for p.curToken.Type != RBRACE && p.curToken.Type != EOF {
+		d := p.parseDeclaration()
+		switch strings.ToLower(d.Name) {
+		case "font-family":
+			face.Family = familyName(d.Value)
+		case "src":
+			face.Sources = fontSources(d.Value)
+		case "font-weight":
+			if d.Value.Keyword != "" {
+				face.Weight = strings.ToLower(d.Value.Keyword)
+			} else if d.Value.Length.Unit == "" {
+				face.Weight = strconv.FormatFloat(d.Value.Length.Quantity, 'f', -1, 64)
+			}
+		case "font-style":
+			if d.Value.Keyword != "" {
+				face.Style = strings.ToLower(d.Value.Keyword)
+			}
+		}
+	}
+	if p.curToken.Type != RBRACE {
+		p.tokenError(RBRACE)
+	}
+	p.nextToken()
+
+	if face.Family == "" || len(face.Sources) == 0 {
+		p.errors = append(p.errors, "invalid @font-face: font-family and src are required")
+		return FontFace{}, false
+	}
+	return face, true
+}
+
+// familyName returns the family of a font-family descriptor or of local(): a string,
+// or keywords like `Go Mono`. It is empty for a list of families.
+func familyName(v Value) string {
+	if v.Text != "" || v.Keyword != "" {
+		return v.Text + v.Keyword
+	}
+	if v.List == nil || v.List.Comma {
+		return ""
+	}
+	words := make([]string, len(v.List.Values))
+	for i, w := range v.List.Values {
+		if w.Keyword == "" {
+			return ""
+		}
+		words[i] = w.Keyword
+	}
+	return strings.Join(words, " ")
+}
+
+// fontSources returns the sources of an src descriptor. Invalid sources are dropped.
+func fontSources(v Value) []FontSource {
+	groups := []Value{v}
+	if v.List != nil && v.List.Comma {
+		groups = v.List.Values
+	}
+
+	var sources []FontSource
+	for _, group := range groups {
+		parts := []Value{group}
+		if group.List != nil {
+			parts = group.List.Values
+		}
+		f := parts[0].Function
+		if f == nil || len(f.Args) != 1 || len(parts) > 2 {
+			continue
+		}
+
+		var source FontSource
+		switch f.Name {
+		case "url":
+			source.URL = f.Args[0].Text
+		case "local":
+			source.Local = familyName(f.Args[0])
+		}
+		if source.URL == "" && source.Local == "" {
+			continue
+		}
+		if len(parts) == 2 {
+			format := parts[1].Function
+			if format == nil || format.Name != "format" || len(format.Args) != 1 {
+				continue
+			}
+			source.Format = strings.ToLower(format.Args[0].Text + format.Args[0].Keyword)
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
@@ -0,0 +1,126 @@
+package css
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStylesheet_imports(t *testing.T) {
+	input := `
+@charset "utf-8";
+@import url(base.css);
+@import "print.css" print;
+@import url("grid.css") supports(display: grid) screen and (min-width: 600px);
+@import url(wide.css) supports((display: block) or (not (display: grid)));
+a { color: red; }
+@import url(late.css);
+@media print { @import url(nested.css); }
+`
+	p := NewParser(strings.NewReader(input))
+	stylesheet := p.ParseStylesheet()
+
+	// the imports following rules are ignored
+	if errors := p.Errors(); len(errors) != 2 {
+		t.Errorf("expected 2 errors, got %v", errors)
+	}
+	if len(stylesheet.Rules) != 1 {
+		t.Errorf("expected 1 rule, got %d", len(stylesheet.Rules))
+	}
+
+	tests := []struct {
+		url      string
+		media    int
+		supports bool
+	}{
+		{"base.css", 0, false},
+		{"print.css", 1, false},
+		{"grid.css", 1, true},
+		{"wide.css", 0, true},
+	}
+	if len(stylesheet.Imports) != len(tests) {
+		t.Fatalf("expected %d imports, got %d", len(tests), len(stylesheet.Imports))
+	}
+	for i, tt := range tests {
+		imp := stylesheet.Imports[i]
+		if imp.URL != tt.url || len(imp.Media) != tt.media || (imp.Supports != nil) != tt.supports {
+			t.Errorf("imports[%d] - expected %s, %d media queries, supports: %v, got %+v", i, tt.url, tt.media, tt.supports, imp)
+		}
+	}
+
+	display := func(d Declaration) bool {
+		return d.Name == "display" && d.Value.Keyword != "grid"
+	}
+	if stylesheet.Imports[2].Supports.Matches(display) {
+		t.Error("expected supports(display: grid) to be false")
+	}
+	if !stylesheet.Imports[3].Supports.Matches(display) {
+		t.Error("expected supports((display: block) or (not (display: grid))) to be true")
+	}
+	if !stylesheet.Imports[1].Media.Matches(Device{Type: "print"}) || stylesheet.Imports[1].Media.Matches(Device{}) {
+		t.Error("expected the print.css import to match print only")
+	}
+}
+
+func TestParseStylesheet_invalidImports(t *testing.T) {
+	for _, input := range []string{
+		"@import;",
+		"@import 12px;",
+		"@import url(a.css) screen {}",
+		"@import url(a.css) supports(display: block;",
+	} {
+		p := NewParser(strings.NewReader(input + " a { color: red; }"))
+		stylesheet := p.ParseStylesheet()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s - expected an error", input)
+		}
+		if len(stylesheet.Imports) != 0 {
+			t.Errorf("%s - expected no import, got %v", input, stylesheet.Imports)
+		}
+	}
+}
+
+func TestParseStylesheet_fontFaces(t *testing.T) {
+	input := `
+@font-face {
+	font-family: "Brand Sans";
+	src: local(Brand Sans), url(fonts/brand.woff2) format("woff2"), url( "fonts/brand.ttf" ) format(truetype);
+	font-weight: 700;
+	font-style: italic;
+	font-display: swap;
+}
+@media print {
+	@font-face { font-family: Print; src: url(print.otf); }
+}
+@font-face { font-family: Missing; }
+@font-face { src: url(anonymous.ttf); }
+`
+	p := NewParser(strings.NewReader(input))
+	stylesheet := p.ParseStylesheet()
+	if errors := p.Errors(); len(errors) != 2 {
+		t.Errorf("expected 2 errors for the invalid font faces, got %v", errors)
+	}
+
+	expected := []FontFace{
+		{
+			Family: "Brand Sans",
+			Sources: []FontSource{
+				{Local: "Brand Sans"},
+				{URL: "fonts/brand.woff2", Format: "woff2"},
+				{URL: "fonts/brand.ttf", Format: "truetype"},
+			},
+			Weight: "700",
+			Style:  "italic",
+		},
+		{
+			Family:     "Print",
+			Sources:    []FontSource{{URL: "print.otf"}},
+			Weight:     "normal",
+			Style:      "normal",
+			Conditions: Conditions{Media: []MediaQueryList{{{Type: "print"}}}},
+		},
+	}
+	if !reflect.DeepEqual(stylesheet.FontFaces, expected) {
+		t.Errorf("expected font faces %+v, got %+v", expected, stylesheet.FontFaces)
+	}
+}
@@ -0,0 +1,207 @@
+package css
+
+import (
+	"math"
+	"strings"
+)
+
+// Transparent is the value of the transparent keyword, a fully transparent black.
+var Transparent = Color{Name: "transparent"}
+
+// NamedColor returns the color of a keyword: one of the named colors, like "rebeccapurple",
+// or transparent. The keywords are case-insensitive.
+// currentColor depends on the element, and is not a named color.
+func NamedColor(keyword string) (Color, bool) {
+	keyword = strings.ToLower(keyword)
+	if keyword == Transparent.Name {
+		return Transparent, true
+	}
+	c, ok := namedColors[keyword]
+	return c, ok
+}
+
+// hexColor returns the color of the digits of a hexadecimal color, like "fff" or "ff000080".
+func hexColor(text string) (Color, bool) {
+	for _, c := range text {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return Color{}, false
+		}
+	}
+
+	color := Color{A: 255}
+	switch len(text) {
+	case 3, 4:
+		color.R = hexToInt(string(text[0]) + string(text[0]))
+		color.G = hexToInt(string(text[1]) + string(text[1]))
+		color.B = hexToInt(string(text[2]) + string(text[2]))
+		if len(text) == 4 {
+			color.A = hexToInt(string(text[3]) + string(text[3]))
+		}
+	case 6, 8:
+		color.R = hexToInt(text[0:2])
+		color.G = hexToInt(text[2:4])
+		color.B = hexToInt(text[4:6])
+		if len(text) == 8 {
+			color.A = hexToInt(text[6:8])
+		}
+	default:
+		return Color{}, false
+	}
+	return normalizeTransparent(color), true
+}
+
+// isColorFunction reports whether a function is one of the functional notations of colors.
+func isColorFunction(name string) bool {
+	switch name {
+	case "rgb", "rgba", "hsl", "hsla", "hwb":
+		return true
+	}
+	return false
+}
+
+// colorFunction returns the color of rgb(), rgba(), hsl(), hsla() and hwb(),
+// written with commas, `rgb(255, 0, 0, 0.5)`, or spaces, `rgb(255 0 0 / 50%)`.
+// See https://www.w3.org/TR/css-color-4/#rgb-functions
+func colorFunction(f *Function) (Color, bool) {
+	channels, alpha, ok := colorArguments(f.Args)
+	if !ok {
+		return Color{}, false
+	}
+
+	var r, g, b float64
+	switch f.Name {
+	case "rgb", "rgba":
+		var values [3]float64
+		for i, c := range channels {
+			if c.Length.Unit == Percent {
+				values[i] = c.Length.Quantity * 255 / 100
+			} else if c.Length.Unit == "" {
+				values[i] = c.Length.Quantity
+			} else {
+				return Color{}, false
+			}
+		}
+		r, g, b = values[0], values[1], values[2]
+	case "hsl", "hsla":
+		hue, ok := angle(channels[0])
+		if !ok {
+			return Color{}, false
+		}
+		r, g, b = hslToRGB(hue, percentage(channels[1]), percentage(channels[2]))
+	case "hwb":
+		hue, ok := angle(channels[0])
+		if !ok {
+			return Color{}, false
+		}
+		r, g, b = hwbToRGB(hue, percentage(channels[1]), percentage(channels[2]))
+	default:
+		return Color{}, false
+	}
+
+	return normalizeTransparent(Color{
+		A: clampChannel(alpha * 255),
+		R: clampChannel(r),
+		G: clampChannel(g),
+		B: clampChannel(b),
+	}), true
+}
+
+// normalizeTransparent returns Transparent for a fully transparent black, like #0000 or rgba(0, 0, 0, 0),
+// the color of the transparent keyword. It keeps a parsed color distinct from the zero Color,
+// so that its value is not mistaken for a unitless 0.
+func normalizeTransparent(c Color) Color {
+	if c == (Color{}) {
+		return Transparent
+	}
+	return c
+}
+
+// colorArguments returns the three channels and the alpha, between 0 and 1, of a color function.
+// The none keyword of the space syntax is read as 0.
+func colorArguments(args []Value) (channels []Value, alpha float64, ok bool) {
+	values := args
+	if len(args) == 1 && args[0].List != nil && !args[0].List.Comma {
+		// space syntax, the alpha follows a slash
+		values = args[0].List.Values
+		if len(values) == 5 && values[3].Keyword == SLASH {
+			values = []Value{values[0], values[1], values[2], values[4]}
+		}
+	}
+	values = append([]Value(nil), values...)
+	if len(values) != 3 && len(values) != 4 {
+		return nil, 0, false
+	}
+
+	for i, v := range values {
+		if strings.ToLower(v.Keyword) == "none" {
+			values[i] = Value{}
+		} else if v.Keyword != "" || v.List != nil || v.Function != nil || v.Text != "" || v.Color != (Color{}) {
+			return nil, 0, false
+		}
+	}
+
+	alpha = 1
+	if len(values) == 4 {
+		alpha = values[3].Length.Quantity
+		if values[3].Length.Unit == Percent {
+			alpha /= 100
+		}
+	}
+	return values[:3], math.Max(0, math.Min(1, alpha)), true
+}
+
+// angle returns the hue of a color in degrees. A number is a number of degrees.
+func angle(v Value) (float64, bool) {
+	switch v.Length.Unit {
+	case "", "deg":
+		return v.Length.Quantity, true
+	case "rad":
+		return v.Length.Quantity * 180 / math.Pi, true
+	case "grad":
+		return v.Length.Quantity * 360 / 400, true
+	case "turn":
+		return v.Length.Quantity * 360, true
+	}
+	return 0, false
+}
+
+// percentage returns a saturation, lightness, whiteness or blackness between 0 and 1.
+// A number is read as a percentage.
+func percentage(v Value) float64 {
+	return math.Max(0, math.Min(1, v.Length.Quantity/100))
+}
+
+func clampChannel(v float64) int {
+	return int(math.Round(math.Max(0, math.Min(255, v))))
+}
+
+// hslToRGB converts a hue in degrees, a saturation and a lightness to channels between 0 and 255.
+// See https://www.w3.org/TR/css-color-4/#hsl-to-rgb
+func hslToRGB(hue, saturation, lightness float64) (r, g, b float64) {
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+
+	f := func(n float64) float64 {
+		k := math.Mod(n+hue/30, 12)
+		a := saturation * math.Min(lightness, 1-lightness)
+		return 255 * (lightness - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1)))
+	}
+	return f(0), f(8), f(4)
+}
+
+// hwbToRGB converts a hue in degrees, a whiteness and a blackness to channels between 0 and 255.
+// See https://www.w3.org/TR/css-color-4/#hwb-to-rgb
+func hwbToRGB(hue, whiteness, blackness float64) (r, g, b float64) {
+	if whiteness+blackness >= 1 {
+		gray := 255 * whiteness / (whiteness + blackness)
+		return gray, gray, gray
+	}
+
+	r, g, b = hslToRGB(hue, 1, 0.5)
+	scale := func(c float64) float64 {
+		return c*(1-whiteness-blackness) + 255*whiteness
+	}
+	return scale(r), scale(g), scale(b)
+}
@@ -0,0 +1,74 @@
+package css
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamedColor(t *testing.T) {
+	if len(namedColors) != 148 {
+		t.Errorf("expected 148 named colors, got %d", len(namedColors))
+	}
+
+	tests := []struct {
+		keyword  string
+		expected Color
+		ok       bool
+	}{
+		{"red", Color{Name: "red", A: 255, R: 255}, true},
+		{"RebeccaPurple", Color{Name: "rebeccapurple", A: 255, R: 0x66, G: 0x33, B: 0x99}, true},
+		{"grey", Color{Name: "grey", A: 255, R: 128, G: 128, B: 128}, true},
+		{"transparent", Transparent, true},
+		{"currentColor", Color{}, false},
+		{"auto", Color{}, false},
+	}
+	for _, tt := range tests {
+		actual, ok := NamedColor(tt.keyword)
+		if ok != tt.ok || actual != tt.expected {
+			t.Errorf("%s - expected: %v %v actual: %v %v", tt.keyword, tt.expected, tt.ok, actual, ok)
+		}
+	}
+}
+
+func TestColorFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Color
+		isErr    bool
+	}{
+		{"rgb(255, 0, 0)", Color{A: 255, R: 255}, false},
+		{"rgb(255 128 0)", Color{A: 255, R: 255, G: 128}, false},
+		{"rgb(100%, 50%, 0%)", Color{A: 255, R: 255, G: 128}, false},
+		{"rgba(0, 0, 255, .5)", Color{A: 128, B: 255}, false},
+		{"rgb(0 0 255 / 25%)", Color{A: 64, B: 255}, false},
+		{"RGB(300, 0, 0)", Color{A: 255, R: 255}, false},
+		{"rgb(none 255 none)", Color{A: 255, G: 255}, false},
+		{"hsl(120, 100%, 50%)", Color{A: 255, G: 255}, false},
+		{"hsl(240deg 100% 50%)", Color{A: 255, B: 255}, false},
+		{"hsl(0.5turn 100% 25% / 0.5)", Color{A: 128, G: 128, B: 128}, false},
+		{"hsla(0, 0%, 100%, 1)", Color{A: 255, R: 255, G: 255, B: 255}, false},
+		{"hwb(0 0% 0%)", Color{A: 255, R: 255}, false},
+		{"hwb(120 20% 20%)", Color{A: 255, R: 51, G: 204, B: 51}, false},
+		{"hwb(0 60% 60%)", Color{A: 255, R: 128, G: 128, B: 128}, false},
+		{"rgba(0, 0, 0, 0)", Transparent, false},
+		{"rgb(0 0 0 / 0%)", Transparent, false},
+		{"rgb(1, 2)", Color{}, true},
+		{"rgb(red, 0, 0)", Color{}, true},
+		{"hsl(1px 0% 0%)", Color{}, true},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader("color: " + tt.input))
+
+		declarations := p.ParseDeclarations()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Fatalf("%s - expected error: %v, got: %v", tt.input, tt.isErr, p.Errors())
+		}
+		if tt.isErr {
+			continue
+		}
+		if actual := declarations[0].Value; actual != (Value{Color: tt.expected}) {
+			t.Errorf("%s - expected: %v actual: %v", tt.input, tt.expected, actual.Color)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package css
+
+// namedColors maps the 148 CSS named colors to their value.
+// See https://www.w3.org/TR/css-color-4/#named-colors
+var namedColors = map[string]Color{
+	"aliceblue":            {Name: "aliceblue", A: 255, R: 240, G: 248, B: 255},
+	"antiquewhite":         {Name: "antiquewhite", A: 255, R: 250, G: 235, B: 215},
+	"aqua":                 {Name: "aqua", A: 255, R: 0, G: 255, B: 255},
+	"aquamarine":           {Name: "aquamarine", A: 255, R: 127, G: 255, B: 212},
+	"azure":                {Name: "azure", A: 255, R: 240, G: 255, B: 255},
+	"beige":                {Name: "beige", A: 255, R: 245, G: 245, B: 220},
+	"bisque":               {Name: "bisque", A: 255, R: 255, G: 228, B: 196},
+	"black":                {Name: "black", A: 255, R: 0, G: 0, B: 0},
+	"blanchedalmond":       {Name: "blanchedalmond", A: 255, R: 255, G: 235, B: 205},
+	"blue":                 {Name: "blue", A: 255, R: 0, G: 0, B: 255},
+	"blueviolet":           {Name: "blueviolet", A: 255, R: 138, G: 43, B: 226},
+	"brown":                {Name: "brown", A: 255, R: 165, G: 42, B: 42},
+	"burlywood":            {Name: "burlywood", A: 255, R: 222, G: 184, B: 135},
+	"cadetblue":            {Name: "cadetblue", A: 255, R: 95, G: 158, B: 160},
+	"chartreuse":           {Name: "chartreuse", A: 255, R: 127, G: 255, B: 0},
+	"chocolate":            {Name: "chocolate", A: 255, R: 210, G: 105, B: 30},
+	"coral":                {Name: "coral", A: 255, R: 255, G: 127, B: 80},
+	"cornflowerblue":       {Name: "cornflowerblue", A: 255, R: 100, G: 149, B: 237},
+	"cornsilk":             {Name: "cornsilk", A: 255, R: 255, G: 248, B: 220},
+	"crimson":              {Name: "crimson", A: 255, R: 220, G: 20, B: 60},
+	"cyan":                 {Name: "cyan", A: 255, R: 0, G: 255, B: 255},
+	"darkblue":             {Name: "darkblue", A: 255, R: 0, G: 0, B: 139},
+	"darkcyan":             {Name: "darkcyan", A: 255, R: 0, G: 139, B: 139},
+	"darkgoldenrod":        {Name: "darkgoldenrod", A: 255, R: 184, G: 134, B: 11},
+	"darkgray":             {Name: "darkgray", A: 255, R: 169, G: 169, B: 169},
+	"darkgreen":            {Name: "darkgreen", A: 255, R: 0, G: 100, B: 0},
+	"darkgrey":             {Name: "darkgrey", A: 255, R: 169, G: 169, B: 169},
+	"darkkhaki":            {Name: "darkkhaki", A: 255, R: 189, G: 183, B: 107},
+	"darkmagenta":          {Name: "darkmagenta", A: 255, R: 139, G: 0, B: 139},
+	"darkolivegreen":       {Name: "darkolivegreen", A: 255, R: 85, G: 107, B: 47},
+	"darkorange":           {Name: "darkorange", A: 255, R: 255, G: 140, B: 0},
+	"darkorchid":           {Name: "darkorchid", A: 255, R: 153, G: 50, B: 204},
+	"darkred":              {Name: "darkred", A: 255, R: 139, G: 0, B: 0},
+	"darksalmon":           {Name: "darksalmon", A: 255, R: 233, G: 150, B: 122},
+	"darkseagreen":         {Name: "darkseagreen", A: 255, R: 143, G: 188, B: 143},
+	"darkslateblue":        {Name: "darkslateblue", A: 255, R: 72, G: 61, B: 139},
+	"darkslategray":        {Name: "darkslategray", A: 255, R: 47, G: 79, B: 79},
+	"darkslategrey":        {Name: "darkslategrey", A: 255, R: 47, G: 79, B: 79},
+	"darkturquoise":        {Name: "darkturquoise", A: 255, R: 0, G: 206, B: 209},
+	"darkviolet":           {Name: "darkviolet", A: 255, R: 148, G: 0, B: 211},
+	"deeppink":             {Name: "deeppink", A: 255, R: 255, G: 20, B: 147},
+	"deepskyblue":          {Name: "deepskyblue", A: 255, R: 0, G: 191, B: 255},
+	"dimgray":              {Name: "dimgray", A: 255, R: 105, G: 105, B: 105},
+	"dimgrey":              {Name: "dimgrey", A: 255, R: 105, G: 105, B: 105},
+	"dodgerblue":           {Name: "dodgerblue", A: 255, R: 30, G: 144, B: 255},
+	"firebrick":            {Name: "firebrick", A: 255, R: 178, G: 34, B: 34},
+	"floralwhite":          {Name: "floralwhite", A: 255, R: 255, G: 250, B: 240},
+	"forestgreen":          {Name: "forestgreen", A: 255, R: 34, G: 139, B: 34},
+	"fuchsia":              {Name: "fuchsia", A: 255, R: 255, G: 0, B: 255},
+	"gainsboro":            {Name: "gainsboro", A: 255, R: 220, G: 220, B: 220},
+	"ghostwhite":           {Name: "ghostwhite", A: 255, R: 248, G: 248, B: 255},
+	"gold":                 {Name: "gold", A: 255, R: 255, G: 215, B: 0},
+	"goldenrod":            {Name: "goldenrod", A: 255, R: 218, G: 165, B: 32},
+	"gray":                 {Name: "gray", A: 255, R: 128, G: 128, B: 128},
+	"green":                {Name: "green", A: 255, R: 0, G: 128, B: 0},
+	"greenyellow":          {Name: "greenyellow", A: 255, R: 173, G: 255, B: 47},
+	"grey":                 {Name: "grey", A: 255, R: 128, G: 128, B: 128},
+	"honeydew":             {Name: "honeydew", A: 255, R: 240, G: 255, B: 240},
+	"hotpink":              {Name: "hotpink", A: 255, R: 255, G: 105, B: 180},
+	"indianred":            {Name: "indianred", A: 255, R: 205, G: 92, B: 92},
+	"indigo":               {Name: "indigo", A: 255, R: 75, G: 0, B: 130},
+	"ivory":                {Name: "ivory", A: 255, R: 255, G: 255, B: 240},
+	"khaki":                {Name: "khaki", A: 255, R: 240, G: 230, B: 140},
+	"lavender":             {Name: "lavender", A: 255, R: 230, G: 230, B: 250},
+	"lavenderblush":        {Name: "lavenderblush", A: 255, R: 255, G: 240, B: 245},
+	"lawngreen":            {Name: "lawngreen", A: 255, R: 124, G: 252, B: 0},
+	"lemonchiffon":         {Name: "lemonchiffon", A: 255, R: 255, G: 250, B: 205},
+	"lightblue":            {Name: "lightblue", A: 255, R: 173, G: 216, B: 230},
+	"lightcoral":           {Name: "lightcoral", A: 255, R: 240, G: 128, B: 128},
+	"lightcyan":            {Name: "lightcyan", A: 255, R: 224, G: 255, B: 255},
+	"lightgoldenrodyellow": {Name: "lightgoldenrodyellow", A: 255, R: 250, G: 250, B: 210},
+	"lightgray":            {Name: "lightgray", A: 255, R: 211, G: 211, B: 211},
+	"lightgreen":           {Name: "lightgreen", A: 255, R: 144, G: 238, B: 144},
+	"lightgrey":            {Name: "lightgrey", A: 255, R: 211, G: 211, B: 211},
+	"lightpink":            {Name: "lightpink", A: 255, R: 255, G: 182, B: 193},
+	"lightsalmon":          {Name: "lightsalmon", A: 255, R: 255, G: 160, B: 122},
+	"lightseagreen":        {Name: "lightseagreen", A: 255, R: 32, G: 178, B: 170},
+	"lightskyblue":         {Name: "lightskyblue", A: 255, R: 135, G: 206, B: 250},
+	"lightslategray":       {Name: "lightslategray", A: 255, R: 119, G: 136, B: 153},
+	"lightslategrey":       {Name: "lightslategrey", A: 255, R: 119, G: 136, B: 153},
+	"lightsteelblue":       {Name: "lightsteelblue", A: 255, R: 176, G: 196, B: 222},
+	"lightyellow":          {Name: "lightyellow", A: 255, R: 255, G: 255, B: 224},
+	"lime":                 {Name: "lime", A: 255, R: 0, G: 255, B: 0},
+	"limegreen":            {Name: "limegreen", A: 255, R: 50, G: 205, B: 50},
+	"linen":                {Name: "linen", A: 255, R: 250, G: 240, B: 230},
+	"magenta":              {Name: "magenta", A: 255, R: 255, G: 0, B: 255},
+	"maroon":               {Name: "maroon", A: 255, R: 128, G: 0, B: 0},
+	"mediumaquamarine":     {Name: "mediumaquamarine", A: 255, R: 102, G: 205, B: 170},
+	"mediumblue":           {Name: "mediumblue", A: 255, R: 0, G: 0, B: 205},
+	"mediumorchid":         {Name: "mediumorchid", A: 255, R: 186, G: 85, B: 211},
+	"mediumpurple":         {Name: "mediumpurple", A: 255, R: 147, G: 112, B: 219},
+	"mediumseagreen":       {Name: "mediumseagreen", A: 255, R: 60, G: 179, B: 113},
+	"mediumslateblue":      {Name: "mediumslateblue", A: 255, R: 123, G: 104, B: 238},
+	"mediumspringgreen":    {Name: "mediumspringgreen", A: 255, R: 0, G: 250, B: 154},
+	"mediumturquoise":      {Name: "mediumturquoise", A: 255, R: 72, G: 209, B: 204},
+	"mediumvioletred":      {Name: "mediumvioletred", A: 255, R: 199, G: 21, B: 133},
+	"midnightblue":         {Name: "midnightblue", A: 255, R: 25, G: 25, B: 112},
+	"mintcream":            {Name: "mintcream", A: 255, R: 245, G: 255, B: 250},
+	"mistyrose":            {Name: "mistyrose", A: 255, R: 255, G: 228, B: 225},
+	"moccasin":             {Name: "moccasin", A: 255, R: 255, G: 228, B: 181},
+	"navajowhite":          {Name: "navajowhite", A: 255, R: 255, G: 222, B: 173},
+	"navy":                 {Name: "navy", A: 255, R: 0, G: 0, B: 128},
+	"oldlace":              {Name: "oldlace", A: 255, R: 253, G: 245, B: 230},
+	"olive":                {Name: "olive", A: 255, R: 128, G: 128, B: 0},
+	"olivedrab":            {Name: "olivedrab", A: 255, R: 107, G: 142, B: 35},
+	"orange":               {Name: "orange", A: 255, R: 255, G: 165, B: 0},
+	"orangered":            {Name: "orangered", A: 255, R: 255, G: 69, B: 0},
+	"orchid":               {Name: "orchid", A: 255, R: 218, G: 112, B: 214},
+	"palegoldenrod":        {Name: "palegoldenrod", A: 255, R: 238, G: 232, B: 170},
+	"palegreen":            {Name: "palegreen", A: 255, R: 152, G: 251, B: 152},
+	"paleturquoise":        {Name: "paleturquoise", A: 255, R: 175, G: 238, B: 238},
+	"palevioletred":        {Name: "palevioletred", A: 255, R: 219, G: 112, B: 147},
+	"papayawhip":           {Name: "papayawhip", A: 255, R: 255, G: 239, B: 213},
+	"peachpuff":            {Name: "peachpuff", A: 255, R: 255, G: 218, B: 185},
+	"peru":                 {Name: "peru", A: 255, R: 205, G: 133, B: 63},
+	"pink":                 {Name: "pink", A: 255, R: 255, G: 192, B: 203},
+	"plum":                 {Name: "plum", A: 255, R: 221, G: 160, B: 221},
+	"powderblue":           {Name: "powderblue", A: 255, R: 176, G: 224, B: 230},
+	"purple":               {Name: "purple", A: 255, R: 128, G: 0, B: 128},
+	"rebeccapurple":        {Name: "rebeccapurple", A: 255, R: 102, G: 51, B: 153},
+	"red":                  {Name: "red", A: 255, R: 255, G: 0, B: 0},
+	"rosybrown":            {Name: "rosybrown", A: 255, R: 188, G: 143, B: 143},
+	"royalblue":            {Name: "royalblue", A: 255, R: 65, G: 105, B: 225},
+	"saddlebrown":          {Name: "saddlebrown", A: 255, R: 139, G: 69, B: 19},
+	"salmon":               {Name: "salmon", A: 255, R: 250, G: 128, B: 114},
+	"sandybrown":           {Name: "sandybrown", A: 255, R: 244, G: 164, B: 96},
+	"seagreen":             {Name: "seagreen", A: 255, R: 46, G: 139, B: 87},
+	"seashell":             {Name: "seashell", A: 255, R: 255, G: 245, B: 238},
+	"sienna":               {Name: "sienna", A: 255, R: 160, G: 82, B: 45},
+	"silver":               {Name: "silver", A: 255, R: 192, G: 192, B: 192},
+	"skyblue":              {Name: "skyblue", A: 255, R: 135, G: 206, B: 235},
+	"slateblue":            {Name: "slateblue", A: 255, R: 106, G: 90, B: 205},
+	"slategray":            {Name: "slategray", A: 255, R: 112, G: 128, B: 144},
+	"slategrey":            {Name: "slategrey", A: 255, R: 112, G: 128, B: 144},
+	"snow":                 {Name: "snow", A: 255, R: 255, G: 250, B: 250},
+	"springgreen":          {Name: "springgreen", A: 255, R: 0, G: 255, B: 127},
+	"steelblue":            {Name: "steelblue", A: 255, R: 70, G: 130, B: 180},
+	"tan":                  {Name: "tan", A: 255, R: 210, G: 180, B: 140},
+	"teal":                 {Name: "teal", A: 255, R: 0, G: 128, B: 128},
+	"thistle":              {Name: "thistle", A: 255, R: 216, G: 191, B: 216},
+	"tomato":               {Name: "tomato", A: 255, R: 255, G: 99, B: 71},
+	"turquoise":            {Name: "turquoise", A: 255, R: 64, G: 224, B: 208},
+	"violet":               {Name: "violet", A: 255, R: 238, G: 130, B: 238},
+	"wheat":                {Name: "wheat", A: 255, R: 245, G: 222, B: 179},
+	"white":                {Name: "white", A: 255, R: 255, G: 255, B: 255},
+	"whitesmoke":           {Name: "whitesmoke", A: 255, R: 245, G: 245, B: 245},
+	"yellow":               {Name: "yellow", A: 255, R: 255, G: 255, B: 0},
+	"yellowgreen":          {Name: "yellowgreen", A: 255, R: 154, G: 205, B: 50},
+}
@@ -14,6 +14,8 @@ const (
 
 	IDENTIFIER = "IDENTIFIER"
 	NUMBER     = "NUMBER"
+	STRING     = "STRING"
+	URL        = "URL" // unquoted url(), like url(fonts/go.ttf), holding the URL only
 
 	STAR         = "*"
 	DOT          = "."
@@ -25,6 +27,20 @@ const (
 	RBRACE       = "}"
 	LPARENTHESIS = "("
 	RPARENTHESIS = ")"
+	GREATER      = ">"
+	PLUS         = "+"
+	TILDE        = "~"
+	LBRACKET     = "["
+	RBRACKET     = "]"
+	EQUAL        = "="
+	PIPE         = "|"
+	CARET        = "^"
+	DOLLAR       = "$"
+	BANG         = "!"
+	SLASH        = "/"
+	PERCENT      = "%"
+	AT           = "@"
+	LESS         = "<"
 )
 
 type CSSToken struct {
@@ -37,6 +53,10 @@ type Lexer struct {
 	position     int
 	readPosition int
 	char         byte
+
+	// spaceBefore tells if whitespace was skipped before the last token,
+	// as it separates the compounds of a selector
+	spaceBefore bool
 }
 
 func NewLexer(input string) *Lexer {
@@ -64,14 +84,27 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+func (l *Lexer) hasSpaceBefore() bool {
+	return l.spaceBefore
+}
+
 func (l *Lexer) NextToken() CSSToken {
 	var tok CSSToken
 
+	start := l.position
 	l.skipWhitespace()
+	l.spaceBefore = l.position > start
+
 	switch l.char {
 	case '*':
 		tok = newToken(STAR, l.char)
 	case '.':
+		if isDigit(l.peekChar()) {
+			// a number without its leading zero, like .5
+			tok.Type = NUMBER
+			tok.Litteral = l.readNumber()
+			return tok
+		}
 		tok = newToken(DOT, l.char)
 	case ',':
 		tok = newToken(COMMA, l.char)
@@ -89,10 +122,39 @@ func (l *Lexer) NextToken() CSSToken {
 		tok = newToken(LPARENTHESIS, l.char)
 	case ')':
 		tok = newToken(RPARENTHESIS, l.char)
+	case '>':
+		tok = newToken(GREATER, l.char)
+	case '+':
+		tok = newToken(PLUS, l.char)
+	case '~':
+		tok = newToken(TILDE, l.char)
+	case '[':
+		tok = newToken(LBRACKET, l.char)
+	case ']':
+		tok = newToken(RBRACKET, l.char)
+	case '=':
+		tok = newToken(EQUAL, l.char)
+	case '|':
+		tok = newToken(PIPE, l.char)
+	case '^':
+		tok = newToken(CARET, l.char)
+	case '$':
+		tok = newToken(DOLLAR, l.char)
+	case '!':
+		tok = newToken(BANG, l.char)
+	case '%':
+		tok = newToken(PERCENT, l.char)
+	case '@':
+		tok = newToken(AT, l.char)
+	case '<':
+		tok = newToken(LESS, l.char)
+	case '"', '\'':
+		tok.Type = STRING
+		tok.Litteral = l.readString()
 	case '/':
 		next := l.peekChar()
 		if next != '*' {
-			tok = newToken(ILLEGAL, l.char)
+			tok = newToken(SLASH, l.char)
 		} else {
 			comment := l.readComment()
 			tok.Litteral = strings.TrimSpace(comment)
@@ -103,9 +165,18 @@ func (l *Lexer) NextToken() CSSToken {
 		tok.Litteral = ""
 		tok.Type = EOF
 	default:
+		if l.char == '-' && l.startsNumber() {
+			tok.Type = NUMBER
+			tok.Litteral = l.readNumber()
+			return tok
+		}
 		if isLetter(l.char) {
 			tok.Litteral = l.readIdentifier()
 			tok.Type = IDENTIFIER
+			if strings.EqualFold(tok.Litteral, "url") && l.char == '(' && !l.quotedURL() {
+				tok.Type = URL
+				tok.Litteral = l.readURL()
+			}
 			return tok
 		} else if isDigit(l.char) {
 			tok.Type = NUMBER
@@ -139,6 +210,62 @@ func (l *Lexer) readComment() string {
 	return comment
 }
 
+// readString reads a quoted string, and returns it without its quotes.
+// Escaped characters, like \" in "a \"quoted\" word", are unescaped.
+func (l *Lexer) readString() string {
+	quote := l.char
+	var b strings.Builder
+	for {
+		l.readChar()
+		switch l.char {
+		case quote, 0:
+			return b.String()
+		case '\\':
+			l.readChar()
+			if l.char == 0 {
+				return b.String()
+			}
+			// an escaped newline continues the string on the next line
+			if l.char != '\n' {
+				b.WriteByte(l.char)
+			}
+		default:
+			b.WriteByte(l.char)
+		}
+	}
+}
+
+// quotedURL tells if the url( at the current position holds a string, like url("go.ttf"),
+// which is lexed as a function.
+func (l *Lexer) quotedURL() bool {
+	for i := l.readPosition; i < len(l.input); i++ {
+		switch l.input[i] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '"', '\'':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// readURL reads an unquoted url(), starting at its parenthesis, and returns the URL it holds.
+func (l *Lexer) readURL() string {
+	l.readChar()
+	l.skipWhitespace()
+	position := l.position
+	for l.char != ')' && l.char != 0 && l.char != ' ' && l.char != '\t' && l.char != '\n' && l.char != '\r' {
+		l.readChar()
+	}
+	url := l.input[position:l.position]
+	l.skipWhitespace()
+	if l.char == ')' {
+		l.readChar()
+	}
+	return url
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isIdentifierPart(l.char) {
@@ -147,8 +274,21 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
+// startsNumber tells if the minus sign at the current position starts a negative number,
+// like -2 or -.5, rather than an identifier like -webkit-box.
+func (l *Lexer) startsNumber() bool {
+	next := l.peekChar()
+	if isDigit(next) {
+		return true
+	}
+	return next == '.' && l.readPosition+1 < len(l.input) && isDigit(l.input[l.readPosition+1])
+}
+
 func (l *Lexer) readNumber() string {
 	position := l.position
+	if l.char == '-' {
+		l.readChar()
+	}
 	for isNumberPart(l.char) {
 		l.readChar()
 	}
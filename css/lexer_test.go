@@ -12,7 +12,11 @@ func TestNextToken(t *testing.T) {
 			margin: 0;
 		}
 		/*    comment example*/
-		>
+		> + ~
+		[lang|="en" i] ^= $= 'it\'s'
+		-1.5em -.5 -webkit-box
+		@media (400px <= width)
+		url( fonts/go.ttf ) url("go.ttf")
 		/#
 	`
 
@@ -54,8 +58,40 @@ func TestNextToken(t *testing.T) {
 		{SEMICOLON, ";"},
 		{RBRACE, "}"},
 		{COMMENT, "comment example"},
-		{ILLEGAL, ">"},
-		{ILLEGAL, "/"},
+		{GREATER, ">"},
+		{PLUS, "+"},
+		{TILDE, "~"},
+		{LBRACKET, "["},
+		{IDENTIFIER, "lang"},
+		{PIPE, "|"},
+		{EQUAL, "="},
+		{STRING, "en"},
+		{IDENTIFIER, "i"},
+		{RBRACKET, "]"},
+		{CARET, "^"},
+		{EQUAL, "="},
+		{DOLLAR, "$"},
+		{EQUAL, "="},
+		{STRING, "it's"},
+		{NUMBER, "-1.5"},
+		{IDENTIFIER, "em"},
+		{NUMBER, "-.5"},
+		{IDENTIFIER, "-webkit-box"},
+		{AT, "@"},
+		{IDENTIFIER, "media"},
+		{LPARENTHESIS, "("},
+		{NUMBER, "400"},
+		{IDENTIFIER, "px"},
+		{LESS, "<"},
+		{EQUAL, "="},
+		{IDENTIFIER, "width"},
+		{RPARENTHESIS, ")"},
+		{URL, "fonts/go.ttf"},
+		{IDENTIFIER, "url"},
+		{LPARENTHESIS, "("},
+		{STRING, "go.ttf"},
+		{RPARENTHESIS, ")"},
+		{SLASH, "/"},
 	}
 
 	l := NewLexer(input)
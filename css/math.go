@@ -0,0 +1,286 @@
+package css
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MathOp is the operation of a node of a math expression.
+type MathOp string
+
+const (
+	MathValue MathOp = "" // a leaf, holding a number, a length or a percentage
+	MathAdd   MathOp = "+"
+	MathSub   MathOp = "-"
+	MathMul   MathOp = "*"
+	MathDiv   MathOp = "/"
+	MathMin   MathOp = "min"
+	MathMax   MathOp = "max"
+	MathClamp MathOp = "clamp"
+)
+
+// MathExpr is the expression tree of the math functions calc(), min(), max() and clamp(),
+// like `calc(100% - 2 * 16px)`. Its lengths are resolved when it is evaluated.
+// See https://www.w3.org/TR/css-values-4/#math
+type MathExpr struct {
+	Op    MathOp
+	Args  []*MathExpr // the operands, or the arguments of min, max and clamp
+	Value Length      // the number or the length of a leaf
+}
+
+// mathType is the type of a math expression, which its operands must agree on.
+type mathType int
+
+const (
+	mathInvalid mathType = iota
+	mathNumber
+	mathLength // lengths and percentages, which resolve to lengths
+)
+
+// typ returns the type of the expression, checking its operands:
+// `1px + 2` and `2px * 3px` are invalid, `2px * 3` is a length.
+func (e *MathExpr) typ() mathType {
+	if e.Op == MathValue {
+		switch u := e.Value.Unit; {
+		case u == "":
+			return mathNumber
+		case u == Percent || u.Absolute() || u.FontRelative() || u.ViewportRelative():
+			return mathLength
+		}
+		return mathInvalid
+	}
+
+	types := make([]mathType, len(e.Args))
+	for i, arg := range e.Args {
+		if types[i] = arg.typ(); types[i] == mathInvalid {
+			return mathInvalid
+		}
+	}
+	switch e.Op {
+	case MathMul:
+		// one of the factors is a number
+		if types[0] == mathNumber {
+			return types[1]
+		}
+		if types[1] == mathNumber {
+			return types[0]
+		}
+		return mathInvalid
+	case MathDiv:
+		// the divisor is a number
+		if types[1] != mathNumber {
+			return mathInvalid
+		}
+		return types[0]
+	}
+	for _, t := range types[1:] {
+		if t != types[0] {
+			return mathInvalid
+		}
+	}
+	return types[0]
+}
+
+// IsNumber tells if the expression evaluates to a number rather than a length, like `calc(1.5 * 2)`.
+func (e *MathExpr) IsNumber() bool {
+	return e.typ() == mathNumber
+}
+
+// HasUnit tells if a length of the expression has a unit matching match,
+// like the percentages which need the containing block to be evaluated.
+func (e *MathExpr) HasUnit(match func(Unit) bool) bool {
+	if e.Op == MathValue {
+		return e.Value.Unit != "" && match(e.Value.Unit)
+	}
+	for _, arg := range e.Args {
+		if arg.HasUnit(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// Eval returns the value of the expression: a number, or a length in pixels,
+// its relative lengths resolved against ctx.
+func (e *MathExpr) Eval(ctx LengthContext) float64 {
+	if e.Op == MathValue {
+		if e.Value.Unit == "" {
+			return e.Value.Quantity
+		}
+		return e.Value.ToPx(ctx)
+	}
+
+	args := make([]float64, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = arg.Eval(ctx)
+	}
+	switch e.Op {
+	case MathAdd:
+		return args[0] + args[1]
+	case MathSub:
+		return args[0] - args[1]
+	case MathMul:
+		return args[0] * args[1]
+	case MathDiv:
+		return args[0] / args[1]
+	case MathMin:
+		min := args[0]
+		for _, a := range args[1:] {
+			min = math.Min(min, a)
+		}
+		return min
+	case MathMax:
+		max := args[0]
+		for _, a := range args[1:] {
+			max = math.Max(max, a)
+		}
+		return max
+	case MathClamp:
+		// the minimum wins over the maximum
+		return math.Max(args[0], math.Min(args[1], args[2]))
+	}
+	return 0
+}
+
+// isMathFunction reports whether a function is one of the math functions.
+func isMathFunction(name string) bool {
+	switch strings.ToLower(name) {
+	case "calc", "min", "max", "clamp":
+		return true
+	}
+	return false
+}
+
+// parseMathFunction parses calc(), min(), max() or clamp(), starting at the name of the function.
+// Its expression must be well typed, like `calc(100% - 2 * 16px)` but not `calc(100% - 2)`.
+func (p *Parser) parseMathFunction() (*MathExpr, bool) {
+	name := strings.ToLower(p.curToken.Litteral)
+	e, ok := p.parseMathArguments()
+	if !ok || e.typ() == mathInvalid {
+		p.errors = append(p.errors, fmt.Sprintf("invalid math expression: %s()", name))
+		return nil, false
+	}
+	return e, true
+}
+
+// parseMathArguments parses a math function, and returns its expression.
+// A calc() is the expression it contains.
+func (p *Parser) parseMathArguments() (*MathExpr, bool) {
+	name := strings.ToLower(p.curToken.Litteral)
+
+	// skip the name and LPARENTHESIS
+	p.nextToken()
+	p.nextToken()
+
+	var args []*MathExpr
+	for {
+		arg, ok := p.parseMathSum()
+		if !ok {
+			return nil, false
+		}
+		args = append(args, arg)
+		if p.curToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if p.curToken.Type != RPARENTHESIS {
+		p.tokenError(RPARENTHESIS)
+		return nil, false
+	}
+	p.nextToken()
+
+	switch name {
+	case "calc":
+		if len(args) != 1 {
+			return nil, false
+		}
+		return args[0], true
+	case "clamp":
+		if len(args) != 3 {
+			return nil, false
+		}
+	}
+	return &MathExpr{Op: MathOp(name), Args: args}, true
+}
+
+// parseMathSum parses terms added or subtracted, like `100% - 2 * 16px`.
+// The + and - operators are surrounded by whitespace, to be told apart from signed numbers.
+func (p *Parser) parseMathSum() (*MathExpr, bool) {
+	e, ok := p.parseMathProduct()
+	if !ok {
+		return nil, false
+	}
+
+	for {
+		var op MathOp
+		switch {
+		case p.curToken.Type == PLUS:
+			op = MathAdd
+		case p.curToken.Type == IDENTIFIER && p.curToken.Litteral == "-":
+			op = MathSub
+		default:
+			return e, true
+		}
+		if !p.curSpace || !p.peekSpace {
+			return nil, false
+		}
+		p.nextToken()
+
+		term, ok := p.parseMathProduct()
+		if !ok {
+			return nil, false
+		}
+		e = &MathExpr{Op: op, Args: []*MathExpr{e, term}}
+	}
+}
+
+// parseMathProduct parses factors multiplied or divided, like `2 * 16px`.
+func (p *Parser) parseMathProduct() (*MathExpr, bool) {
+	e, ok := p.parseMathValue()
+	if !ok {
+		return nil, false
+	}
+
+	for p.curToken.Type == STAR || p.curToken.Type == SLASH {
+		op := MathMul
+		if p.curToken.Type == SLASH {
+			op = MathDiv
+		}
+		p.nextToken()
+
+		factor, ok := p.parseMathValue()
+		if !ok {
+			return nil, false
+		}
+		e = &MathExpr{Op: op, Args: []*MathExpr{e, factor}}
+	}
+	return e, true
+}
+
+// parseMathValue parses a number, a length, a percentage, a parenthesized expression
+// or a nested math function.
+func (p *Parser) parseMathValue() (*MathExpr, bool) {
+	switch p.curToken.Type {
+	case NUMBER:
+		return &MathExpr{Value: p.parseLength()}, true
+	case LPARENTHESIS:
+		p.nextToken()
+		e, ok := p.parseMathSum()
+		if !ok {
+			return nil, false
+		}
+		if p.curToken.Type != RPARENTHESIS {
+			p.tokenError(RPARENTHESIS)
+			return nil, false
+		}
+		p.nextToken()
+		return e, true
+	case IDENTIFIER:
+		if isMathFunction(p.curToken.Litteral) && p.peekToken.Type == LPARENTHESIS && !p.peekSpace {
+			return p.parseMathArguments()
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,81 @@
+package css
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMathFunctions(t *testing.T) {
+	ctx := LengthContext{FontSize: 20, RootFontSize: 16, PercentBase: 200, ViewportWidth: 800, ViewportHeight: 600}
+	tests := []struct {
+		input    string
+		expected float64
+		number   bool
+		isErr    bool
+	}{
+		{"calc(100% - 2 * 16px)", 168, false, false},
+		{"calc(1em + 2px * 3)", 26, false, false},
+		{"calc((1em + 2px) * 3)", 66, false, false},
+		{"calc(100% / 4 - -2px)", 52, false, false},
+		{"CALC(1.5 * 2)", 3, true, false},
+		{"calc(calc(1px + 1px) * 2)", 4, false, false},
+		{"min(10px, 2em, 50%)", 10, false, false},
+		{"max(10px, 2em, 5%)", 40, false, false},
+		{"clamp(12px, 2vw, 20px)", 16, false, false},
+		{"clamp(12px, 1vw, 20px)", 12, false, false},
+		{"clamp(12px, 5vw, 20px)", 20, false, false},
+		{"calc(min(1rem, 10px) + 1px)", 11, false, false},
+		{"calc(1px + 2)", 0, false, true},
+		{"calc(2px * 3px)", 0, false, true},
+		{"calc(2 / 1px)", 0, false, true},
+		{"calc(1px +2px)", 0, false, true},
+		{"calc(1px -2px)", 0, false, true},
+		{"calc(1px, 2px)", 0, false, true},
+		{"clamp(1px, 2px)", 0, false, true},
+		{"calc(10deg)", 0, false, true},
+		{"calc(1px + auto)", 0, false, true},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader("width: " + tt.input + "; height: 1px"))
+
+		declarations := p.ParseDeclarations()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Fatalf("%s - expected error: %v, got: %v", tt.input, tt.isErr, p.Errors())
+		}
+		if tt.isErr {
+			// the invalid declaration is dropped, the next one is kept
+			if len(declarations) != 1 || declarations[0].Name != "height" {
+				t.Errorf("%s - expected the height declaration only, got %v", tt.input, declarations)
+			}
+			continue
+		}
+
+		value := declarations[0].Value
+		if value.Math == nil {
+			t.Fatalf("%s - expected a math expression, got %v", tt.input, value)
+		}
+		if value.Math.IsNumber() != tt.number {
+			t.Errorf("%s - expected number: %v", tt.input, tt.number)
+		}
+		if actual := value.ToPx(ctx); math.Abs(actual-tt.expected) > 1e-9 {
+			t.Errorf("%s - expected: %v actual: %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestMathExpr_HasUnit(t *testing.T) {
+	p := NewParser(strings.NewReader("width: calc(100% - max(1em, 2vw))"))
+	e := p.ParseDeclarations()[0].Value.Math
+
+	if !e.HasUnit(Unit.ViewportRelative) {
+		t.Error("expected a viewport length")
+	}
+	if !e.HasUnit(func(u Unit) bool { return u == Percent }) {
+		t.Error("expected a percentage")
+	}
+	if e.HasUnit(Unit.Absolute) {
+		t.Error("expected no absolute length")
+	}
+}
@@ -0,0 +1,443 @@
+package css
+
+import (
+	"strings"
+)
+
+// MediaQueryList is the comma separated list of media queries of an @media rule,
+// like `screen and (min-width: 600px), print`. It matches if one of its queries matches.
+// See https://www.w3.org/TR/mediaqueries-4/
+type MediaQueryList []MediaQuery
+
+// MediaQuery tests the type and the features of the device, like `screen and (min-width: 600px)`.
+type MediaQuery struct {
+	Not  bool   // the query matches when its type and condition do not
+	Only bool   // the only keyword, which hides the query from legacy browsers
+	Type string // lowercase media type, like screen or print, empty for all
+
+	// Condition holds the features the device must have, nil if the query only has a type
+	Condition *MediaCondition
+}
+
+// notAll is the query replacing an invalid media query, which matches nothing.
+var notAll = MediaQuery{Not: true, Type: "all"}
+
+// MediaCondition is a feature, or conditions combined with and, or and not,
+// like `(min-width: 600px) and (orientation: landscape)`.
+type MediaCondition struct {
+	Op         string // "and", "or" or "not", empty for a feature
+	Conditions []*MediaCondition
+	Feature    *MediaFeature
+}
+
+// MediaFeature tests a feature of the device, like (min-width: 600px), (400px <= width < 700px)
+// or (orientation: landscape). Without comparison, like (color), it tests that the feature is not zero or none.
+type MediaFeature struct {
+	Name        string // lowercase, without the min- and max- prefixes
+	Comparisons []MediaComparison
+}
+
+// MediaComparison compares the feature, on the left of Op, to Value.
+// `(min-width: 600px)` and `(600px <= width)` are both the comparison `width >= 600px`.
+type MediaComparison struct {
+	Op    string // "=", "<", "<=", ">" or ">="
+	Value Value
+}
+
+// Device describes where a document is rendered, to evaluate media queries.
+type Device struct {
+	Type          string  // media type, screen when empty
+	Width, Height float64 // size of the viewport in pixels
+	Resolution    float64 // pixels of the device per CSS pixel, 1 when zero
+	ColorScheme   string  // "light" or "dark", light when empty
+}
+
+func (d Device) mediaType() string {
+	if d.Type == "" {
+		return "screen"
+	}
+	return strings.ToLower(d.Type)
+}
+
+// MatchesMedia reports whether the media queries of the @media rules containing the rule
+// all match the device. A rule outside of @media always matches.
+func (c Conditions) MatchesMedia(d Device) bool {
+	for _, list := range c.Media {
+		if !list.Matches(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether one of the queries matches the device. An empty list matches all devices.
+func (l MediaQueryList) Matches(d Device) bool {
+	if len(l) == 0 {
+		return true
+	}
+	for _, q := range l {
+		if q.Matches(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the device has the type and the features of the query.
+func (q MediaQuery) Matches(d Device) bool {
+	matches := q.Condition == nil || q.Condition.Matches(d)
+	if q.Type != "" && q.Type != "all" && q.Type != d.mediaType() {
+		matches = false
+	}
+	return matches != q.Not
+}
+
+// Matches reports whether the device meets the condition.
+func (c *MediaCondition) Matches(d Device) bool {
+	switch c.Op {
+	case "not":
+		return !c.Conditions[0].Matches(d)
+	case "and":
+		for _, condition := range c.Conditions {
+			if !condition.Matches(d) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, condition := range c.Conditions {
+			if condition.Matches(d) {
+				return true
+			}
+		}
+		return false
+	}
+	return c.Feature.Matches(d)
+}
+
+// Matches reports whether the device has the feature. Unknown features never match.
+func (f *MediaFeature) Matches(d Device) bool {
+	// the lengths of media queries are relative to the initial font size
+	lengths := LengthContext{FontSize: 16, RootFontSize: 16, ViewportWidth: d.Width, ViewportHeight: d.Height}
+
+	switch f.Name {
+	case "width":
+		return f.compare(d.Width, func(v Value) (float64, bool) { return mediaLength(v, lengths) })
+	case "height":
+		return f.compare(d.Height, func(v Value) (float64, bool) { return mediaLength(v, lengths) })
+	case "resolution":
+		resolution := d.Resolution
+		if resolution == 0 {
+			resolution = 1
+		}
+		return f.compare(resolution, mediaResolution)
+	case "orientation":
+		orientation := "landscape"
+		if d.Height >= d.Width {
+			orientation = "portrait"
+		}
+		return f.is(orientation)
+	case "prefers-color-scheme":
+		scheme := d.ColorScheme
+		if scheme == "" {
+			scheme = "light"
+		}
+		return f.is(scheme)
+	}
+	return false
+}
+
+// compare compares a numeric feature to the values of the feature, converted by value.
+func (f *MediaFeature) compare(feature float64, value func(Value) (float64, bool)) bool {
+	if len(f.Comparisons) == 0 {
+		return feature != 0
+	}
+	for _, c := range f.Comparisons {
+		v, ok := value(c.Value)
+		if !ok {
+			return false
+		}
+		var matches bool
+		switch c.Op {
+		case "=":
+			matches = feature == v
+		case "<":
+			matches = feature < v
+		case "<=":
+			matches = feature <= v
+		case ">":
+			matches = feature > v
+		case ">=":
+			matches = feature >= v
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+// is tests a discrete feature, like orientation, which only compares to keywords.
+func (f *MediaFeature) is(feature string) bool {
+	if len(f.Comparisons) == 0 {
+		return true
+	}
+	c := f.Comparisons[0]
+	return len(f.Comparisons) == 1 && c.Op == "=" && strings.EqualFold(c.Value.Keyword, feature)
+}
+
+// mediaLength returns a length of a media query in pixels.
+func mediaLength(v Value, lengths LengthContext) (float64, bool) {
+	unit := v.Length.Unit
+	if v.Keyword != "" || unit == "" && v.Length.Quantity != 0 || unit == Percent {
+		return 0, false
+	}
+	if unit != "" && !unit.Absolute() && !unit.FontRelative() && !unit.ViewportRelative() {
+		return 0, false
+	}
+	return v.Length.ToPx(lengths), true
+}
+
+// mediaResolution returns a resolution in dots per pixel, from dppx, x, dpi or dpcm.
+func mediaResolution(v Value) (float64, bool) {
+	switch v.Length.Unit {
+	case "dppx", "x":
+		return v.Length.Quantity, true
+	case "dpi":
+		return v.Length.Quantity / 96, true
+	case "dpcm":
+		return v.Length.Quantity * 2.54 / 96, true
+	}
+	return 0, false
+}
+
+// parseMediaQueryList parses the comma separated media queries of an @media rule, up to its block.
+// An invalid query is replaced by `not all`, which matches nothing, and the others are kept.
+func (p *Parser) parseMediaQueryList() MediaQueryList {
+	list := MediaQueryList{}
+	for !isMediaQueryListEnd(p.curToken.Type) {
+		query, ok := p.parseMediaQuery()
+		if !ok || p.curToken.Type != COMMA && !isMediaQueryListEnd(p.curToken.Type) {
+			p.errors = append(p.errors, "invalid media query")
+			query = notAll
+			// skip to the next query
+			depth := 0
+			for !isMediaQueryListEnd(p.curToken.Type) && (depth > 0 || p.curToken.Type != COMMA) {
+				switch p.curToken.Type {
+				case LPARENTHESIS:
+					depth++
+				case RPARENTHESIS:
+					depth--
+				}
+				p.nextToken()
+			}
+		}
+		list = append(list, query)
+
+		if p.curToken.Type == COMMA {
+			p.nextToken()
+		}
+	}
+	return list
+}
+
+func isMediaQueryListEnd(t CSSTokenType) bool {
+	return t == LBRACE || t == SEMICOLON || t == EOF
+}
+
+// parseMediaQuery parses `[not | only] type [and condition]`, or a condition.
+func (p *Parser) parseMediaQuery() (MediaQuery, bool) {
+	q := MediaQuery{}
+	if p.curToken.Type == LPARENTHESIS || isKeyword(p.curToken, "not") && p.peekToken.Type == LPARENTHESIS {
+		condition, ok := p.parseMediaCondition(true)
+		q.Condition = condition
+		return q, ok
+	}
+
+	if isKeyword(p.curToken, "not") || isKeyword(p.curToken, "only") {
+		q.Not = isKeyword(p.curToken, "not")
+		q.Only = !q.Not
+		p.nextToken()
+	}
+	if p.curToken.Type != IDENTIFIER {
+		return q, false
+	}
+	q.Type = strings.ToLower(p.curToken.Litteral)
+	switch q.Type {
+	case "and", "or", "not", "only":
+		return q, false
+	}
+	p.nextToken()
+
+	if isKeyword(p.curToken, "and") {
+		p.nextToken()
+		// the conditions following a type cannot be combined with or
+		condition, ok := p.parseMediaCondition(false)
+		q.Condition = condition
+		return q, ok
+	}
+	return q, true
+}
+
+// parseMediaCondition parses `not (condition)`, or conditions in parentheses combined with and,
+// or with or, but not both.
+func (p *Parser) parseMediaCondition(allowOr bool) (*MediaCondition, bool) {
+	if isKeyword(p.curToken, "not") {
+		p.nextToken()
+		condition, ok := p.parseMediaInParens()
+		return &MediaCondition{Op: "not", Conditions: []*MediaCondition{condition}}, ok
+	}
+
+	first, ok := p.parseMediaInParens()
+	if !ok {
+		return nil, false
+	}
+	conditions := []*MediaCondition{first}
+	op := ""
+	for isKeyword(p.curToken, "and") || isKeyword(p.curToken, "or") {
+		next := strings.ToLower(p.curToken.Litteral)
+		if op != "" && next != op || next == "or" && !allowOr {
+			return nil, false
+		}
+		op = next
+		p.nextToken()
+
+		condition, ok := p.parseMediaInParens()
+		if !ok {
+			return nil, false
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if op == "" {
+		return first, true
+	}
+	return &MediaCondition{Op: op, Conditions: conditions}, true
+}
+
+// parseMediaInParens parses a condition or a feature in parentheses.
+func (p *Parser) parseMediaInParens() (*MediaCondition, bool) {
+	if p.curToken.Type != LPARENTHESIS {
+		return nil, false
+	}
+	p.nextToken()
+
+	var condition *MediaCondition
+	ok := false
+	if p.curToken.Type == LPARENTHESIS || isKeyword(p.curToken, "not") {
+		condition, ok = p.parseMediaCondition(true)
+	} else {
+		var feature *MediaFeature
+		feature, ok = p.parseMediaFeature()
+		condition = &MediaCondition{Feature: feature}
+	}
+	if !ok || p.curToken.Type != RPARENTHESIS {
+		return nil, false
+	}
+	p.nextToken()
+	return condition, true
+}
+
+// parseMediaFeature parses the inside of `(name)`, `(name: value)`, `(name < value)`,
+// `(value < name)` or `(value < name < value)`.
+func (p *Parser) parseMediaFeature() (*MediaFeature, bool) {
+	if p.curToken.Type == IDENTIFIER {
+		f := &MediaFeature{Name: strings.ToLower(p.curToken.Litteral)}
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case RPARENTHESIS:
+			return f, true
+		case COLON:
+			p.nextToken()
+			value, ok := p.parseMediaValue()
+			op := "="
+			if strings.HasPrefix(f.Name, "min-") {
+				f.Name, op = f.Name[len("min-"):], ">="
+			} else if strings.HasPrefix(f.Name, "max-") {
+				f.Name, op = f.Name[len("max-"):], "<="
+			}
+			f.Comparisons = []MediaComparison{{Op: op, Value: value}}
+			return f, ok
+		}
+
+		op, ok := p.parseComparison()
+		if !ok {
+			return nil, false
+		}
+		value, ok := p.parseMediaValue()
+		f.Comparisons = []MediaComparison{{Op: op, Value: value}}
+		return f, ok
+	}
+
+	// the feature is between two values, like 400px <= width < 700px
+	value, ok := p.parseMediaValue()
+	if !ok {
+		return nil, false
+	}
+	op, ok := p.parseComparison()
+	if !ok || p.curToken.Type != IDENTIFIER {
+		return nil, false
+	}
+	f := &MediaFeature{Name: strings.ToLower(p.curToken.Litteral)}
+	f.Comparisons = []MediaComparison{{Op: flipComparison(op), Value: value}}
+	p.nextToken()
+
+	if p.curToken.Type == RPARENTHESIS {
+		return f, true
+	}
+	second, ok := p.parseComparison()
+	if !ok || second[0] != op[0] || op == "=" {
+		// both operators go in the same direction
+		return nil, false
+	}
+	value, ok = p.parseMediaValue()
+	f.Comparisons = append(f.Comparisons, MediaComparison{Op: second, Value: value})
+	return f, ok
+}
+
+// parseMediaValue parses the value of a feature: a length, a number, a resolution or a keyword.
+func (p *Parser) parseMediaValue() (Value, bool) {
+	switch p.curToken.Type {
+	case NUMBER:
+		return Value{Length: p.parseLength()}, true
+	case IDENTIFIER:
+		v := Value{Keyword: p.curToken.Litteral}
+		p.nextToken()
+		return v, true
+	}
+	return Value{}, false
+}
+
+// parseComparison parses the operator of a range, like <= in (400px <= width).
+func (p *Parser) parseComparison() (string, bool) {
+	switch p.curToken.Type {
+	case LESS, GREATER:
+		op := p.curToken.Litteral
+		if p.peekToken.Type == EQUAL && !p.peekSpace {
+			p.nextToken()
+			op += "="
+		}
+		p.nextToken()
+		return op, true
+	case EQUAL:
+		p.nextToken()
+		return "=", true
+	}
+	return "", false
+}
+
+// flipComparison returns the operator comparing the right side to the left one: `400px < width` is `width > 400px`.
+func flipComparison(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
@@ -0,0 +1,131 @@
+package css
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMediaQueries(t *testing.T) {
+	narrow := Device{Width: 300, Height: 500}
+	wide := Device{Width: 800, Height: 600, Resolution: 2, ColorScheme: "dark"}
+	print := Device{Type: "print", Width: 800, Height: 1000}
+
+	tests := []struct {
+		query               string
+		narrow, wide, print bool
+		isErr               bool
+	}{
+		{"all", true, true, true, false},
+		{"screen", true, true, false, false},
+		{"PRINT", false, false, true, false},
+		{"not print", true, true, false, false},
+		{"only screen", true, true, false, false},
+		{"screen, print", true, true, true, false},
+		{"(min-width: 600px)", false, true, true, false},
+		{"(max-width: 599.9px)", true, false, false, false},
+		{"(width: 300px)", true, false, false, false},
+		{"(min-width: 20em)", false, true, true, false},
+		{"screen and (min-width: 600px)", false, true, false, false},
+		{"not screen and (min-width: 600px)", true, false, true, false},
+		{"(min-width: 600px) and (max-height: 700px)", false, true, false, false},
+		{"(max-width: 400px) or (min-height: 900px)", true, false, true, false},
+		{"not (min-width: 600px)", true, false, false, false},
+		{"((min-width: 600px) and (not (orientation: portrait)))", false, true, false, false},
+		{"(width >= 600px)", false, true, true, false},
+		{"(400px <= width)", false, true, true, false},
+		{"(200px < width <= 300px)", true, false, false, false},
+		{"(800px > width)", true, false, false, false},
+		{"(orientation: portrait)", true, false, true, false},
+		{"(orientation: landscape)", false, true, false, false},
+		{"(min-resolution: 2dppx)", false, true, false, false},
+		{"(resolution: 96dpi)", true, false, true, false},
+		{"(prefers-color-scheme: dark)", false, true, false, false},
+		{"(prefers-color-scheme: light)", true, false, true, false},
+		{"(width)", true, true, true, false},
+		{"(hover: hover)", false, false, false, false},
+		{"(min-width: 50%)", false, false, false, false},
+
+		// invalid queries are `not all`, the valid ones of the list are kept
+		{"screen and", false, false, false, true},
+		{"(min-width: 600px) and (max-width: 700px) or (color)", false, false, false, true},
+		{"screen and (min-width: 600px) or (max-width: 400px)", false, false, false, true},
+		{"(400px < width > 700px)", false, false, false, true},
+		{"(min-width 600px), print", false, false, true, true},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader("@media " + tt.query + " { a { color: red; } }"))
+		stylesheet := p.ParseStylesheet()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Errorf("%s - expected error: %v, got: %v", tt.query, tt.isErr, p.Errors())
+			continue
+		}
+		if len(stylesheet.Rules) != 1 {
+			t.Errorf("%s - expected 1 rule, got %d", tt.query, len(stylesheet.Rules))
+			continue
+		}
+		rule := stylesheet.Rules[0]
+		for _, d := range []struct {
+			name     string
+			device   Device
+			expected bool
+		}{
+			{"narrow", narrow, tt.narrow},
+			{"wide", wide, tt.wide},
+			{"print", print, tt.print},
+		} {
+			if actual := rule.MatchesMedia(d.device); actual != d.expected {
+				t.Errorf("%s - %s device: expected %v, got %v", tt.query, d.name, d.expected, actual)
+			}
+		}
+	}
+}
+
+func TestParseStylesheet_media(t *testing.T) {
+	input := `
+a { color: red; }
+@charset "utf-8";
+@media screen {
+	b { color: red; }
+	@media (min-width: 600px) {
+		i { color: red; }
+	}
+	@keyframes fade { from { color: red; } to { color: blue; } }
+}
+@media print { p { color: red; } }
+u { color: red; }`
+	p := NewParser(strings.NewReader(input))
+	stylesheet := p.ParseStylesheet()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	tests := []struct {
+		tag   string
+		media int
+	}{
+		{"a", 0},
+		{"b", 1},
+		{"i", 2},
+		{"p", 1},
+		{"u", 0},
+	}
+	if len(stylesheet.Rules) != len(tests) {
+		t.Fatalf("expected %d rules, got %d", len(tests), len(stylesheet.Rules))
+	}
+	for i, tt := range tests {
+		rule := stylesheet.Rules[i]
+		if rule.Selectors[0].TagName != tt.tag || len(rule.Media) != tt.media {
+			t.Errorf("rules[%d] - expected %s in %d @media, got %s in %d", i, tt.tag, tt.media, rule.Selectors[0].TagName, len(rule.Media))
+		}
+	}
+
+	narrow := Device{Width: 300, Height: 500}
+	if !stylesheet.Rules[1].MatchesMedia(narrow) || stylesheet.Rules[2].MatchesMedia(narrow) {
+		t.Errorf("expected the nested @media rule to match both queries")
+	}
+	if !stylesheet.Rules[2].MatchesMedia(Device{Width: 800}) {
+		t.Errorf("expected the nested @media rule to match a wide screen")
+	}
+}
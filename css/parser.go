@@ -5,14 +5,19 @@ import (
 	"io"
 	"io/ioutil"
 	"strconv"
+	"strings"
 )
 
 type Parser struct {
-	lexer *Lexer
+	lexer tokenizer
 
 	curToken  CSSToken
 	peekToken CSSToken
 
+	// whitespace found before the current and the peek token
+	curSpace  bool
+	peekSpace bool
+
 	errors []string
 }
 
@@ -22,7 +27,18 @@ func NewParser(r io.Reader) *Parser {
 		return nil
 	}
 
-	lexer := NewLexer(string(b))
+	return newParser(NewLexer(string(b)))
+}
+
+// tokenizer is the source of the tokens of a parser: a lexer, or the tokens of a raw value.
+type tokenizer interface {
+	NextToken() CSSToken
+
+	// hasSpaceBefore tells if whitespace was found before the last token
+	hasSpaceBefore() bool
+}
+
+func newParser(lexer tokenizer) *Parser {
 	p := Parser{
 		lexer:  lexer,
 		errors: []string{},
@@ -45,7 +61,9 @@ func (p *Parser) tokenError(expected CSSTokenType) {
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
+	p.curSpace = p.peekSpace
 	p.peekToken = p.lexer.NextToken()
+	p.peekSpace = p.lexer.hasSpaceBefore()
 
 	// Skip comments
 	if p.curToken.Type == COMMENT {
@@ -58,18 +76,150 @@ func (p *Parser) ParseStylesheet() *Stylesheet {
 	stylesheet.Rules = []Rule{}
 
 	for p.curToken.Type != EOF {
+		p.parseRules(stylesheet, Conditions{})
+		if p.curToken.Type == RBRACE {
+			// unbalanced brace
+			p.tokenError(IDENTIFIER)
+			p.nextToken()
+		}
+	}
+
+	return stylesheet
+}
+
+// parseRules parses the rules of a stylesheet, or of the block of an @media or @supports rule,
+// up to its closing brace, and adds them to the stylesheet.
+// The rules get the conditions of the @media and @supports rules containing them.
+func (p *Parser) parseRules(stylesheet *Stylesheet, conditions Conditions) {
+	for p.curToken.Type != EOF && p.curToken.Type != RBRACE {
+		if p.curToken.Type == AT {
+			p.parseAtRule(stylesheet, conditions)
+			continue
+		}
+
 		rule := p.parseRule()
 		if len(rule.Declarations) != 0 && len(rule.Selectors) != 0 {
+			rule.Conditions = conditions
 			stylesheet.Rules = append(stylesheet.Rules, rule)
 		}
 	}
+}
 
-	return stylesheet
+// parseAtRule parses an at-rule, like `@media screen { ... }`, and adds what it holds to the stylesheet.
+// Unknown at-rules are skipped.
+func (p *Parser) parseAtRule(stylesheet *Stylesheet, conditions Conditions) {
+	p.nextToken()
+	if p.curToken.Type != IDENTIFIER || p.curSpace {
+		p.tokenError(IDENTIFIER)
+		p.skipAtRule()
+		return
+	}
+	name := strings.ToLower(p.curToken.Litteral)
+	p.nextToken()
+
+	switch name {
+	case "media":
+		queries := p.parseMediaQueryList()
+		// the rules of nested @media rules match all the queries
+		p.parseConditionalBlock(stylesheet, conditions.withMedia(queries))
+		return
+
+	case "supports":
+		condition, ok := p.parseSupportsCondition()
+		if !ok {
+			p.errors = append(p.errors, "invalid @supports condition")
+			p.skipAtRule()
+			return
+		}
+		p.parseConditionalBlock(stylesheet, conditions.withSupports(condition))
+		return
+
+	case "import":
+		// @import comes before the other rules, at the top level of the stylesheet
+		if len(conditions.Media) > 0 || len(conditions.Supports) > 0 || len(stylesheet.Rules) > 0 || len(stylesheet.FontFaces) > 0 {
+			p.errors = append(p.errors, "@import must come before the other rules")
+			p.skipAtRule()
+			return
+		}
+		if imp, ok := p.parseImport(); ok {
+			stylesheet.Imports = append(stylesheet.Imports, imp)
+		}
+		return
+
+	case "font-face":
+		if face, ok := p.parseFontFace(); ok {
+			face.Conditions = conditions
+			stylesheet.FontFaces = append(stylesheet.FontFaces, face)
+		}
+		return
+	}
+
+	p.skipAtRule()
+}
+
+// parseConditionalBlock parses the block of an @media or @supports rule, whose rules get the conditions.
+func (p *Parser) parseConditionalBlock(stylesheet *Stylesheet, conditions Conditions) {
+	if p.curToken.Type != LBRACE {
+		p.tokenError(LBRACE)
+		p.skipAtRule()
+		return
+	}
+	p.nextToken()
+
+	p.parseRules(stylesheet, conditions)
+	if p.curToken.Type != RBRACE {
+		p.tokenError(RBRACE)
+		return
+	}
+	p.nextToken()
+}
+
+// skipAtRule skips the rest of an at-rule: up to its semicolon, or to the end of its block.
+func (p *Parser) skipAtRule() {
+	depth := 0
+	for p.curToken.Type != EOF {
+		switch p.curToken.Type {
+		case SEMICOLON:
+			if depth == 0 {
+				p.nextToken()
+				return
+			}
+		case LBRACE:
+			depth++
+		case RBRACE:
+			if depth == 0 {
+				// the end of the block containing the at-rule
+				return
+			}
+			depth--
+			if depth == 0 {
+				p.nextToken()
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
+// ParseDeclarations parses a list of declarations without selectors nor braces,
+// like the content of a style attribute: `color: red; margin: 4px`.
+func (p *Parser) ParseDeclarations() []Declaration {
+	declarations := []Declaration{}
+	for p.curToken.Type != EOF {
+		declaration := p.parseDeclaration()
+		if declaration.Name != "" {
+			declarations = append(declarations, declaration)
+		}
+	}
+	return declarations
 }
 
 func (p *Parser) parseRule() Rule {
 	rule := Rule{}
+	errors := len(p.errors)
 	rule.Selectors = p.parseSelectors()
+	// a rule with an invalid selector, like an unknown pseudo-class, is ignored
+	invalid := len(p.errors) > errors
 
 	if p.curToken.Type != LBRACE {
 		p.tokenError(LBRACE)
@@ -89,6 +239,9 @@ func (p *Parser) parseRule() Rule {
 	}
 	p.nextToken()
 
+	if invalid {
+		rule.Selectors = nil
+	}
 	return rule
 }
 
@@ -103,6 +256,11 @@ func (p *Parser) parseSelectors() []Selector {
 			p.nextToken()
 			continue
 		}
+		if p.curToken.Type == RPARENTHESIS {
+			// unbalanced parenthesis
+			p.tokenError(LBRACE)
+			p.nextToken()
+		}
 	}
 
 	return selectors
@@ -112,17 +270,32 @@ func (p *Parser) parseSelector() Selector {
 	selector := Selector{
 		Classes: []string{},
 	}
+	empty := true // nothing parsed yet in the current compound
 
-	for p.curToken.Type != COMMA && p.curToken.Type != LBRACE && p.curToken.Type != EOF {
-		switch p.curToken.Type {
-		case STAR:
-			selector.TagName = "*"
+	for p.curToken.Type != COMMA && p.curToken.Type != LBRACE && p.curToken.Type != RPARENTHESIS && p.curToken.Type != EOF {
+		if selector.PseudoElement != "" {
+			// nothing can follow a pseudo-element
+			p.tokenError(LBRACE)
 			p.nextToken()
-			continue
-		case IDENTIFIER:
+			return selector
+		}
+
+		// Whitespace between two compounds is the descendant combinator
+		if p.curSpace && !empty && !isCombinator(p.curToken.Type) {
+			selector = combine(selector, Descendant)
+			empty = true
+		}
+
+		switch p.curToken.Type {
+		case STAR, IDENTIFIER:
+			if !empty {
+				// the type or universal selector must start its compound, like `*.a` but not `.a*`
+				p.tokenError(LBRACE)
+				p.nextToken()
+				return selector
+			}
 			selector.TagName = p.curToken.Litteral
 			p.nextToken()
-			continue
 		case HASH:
 			if p.peekToken.Type != IDENTIFIER {
 				p.tokenError(IDENTIFIER)
@@ -132,7 +305,6 @@ func (p *Parser) parseSelector() Selector {
 			selector.ID = p.peekToken.Litteral
 			p.nextToken()
 			p.nextToken()
-			continue
 		case DOT:
 			if p.peekToken.Type != IDENTIFIER {
 				p.tokenError(IDENTIFIER)
@@ -142,6 +314,36 @@ func (p *Parser) parseSelector() Selector {
 			selector.Classes = append(selector.Classes, p.peekToken.Litteral)
 			p.nextToken()
 			p.nextToken()
+		case LBRACKET:
+			attribute, ok := p.parseAttributeSelector()
+			if !ok {
+				return selector
+			}
+			selector.Attributes = append(selector.Attributes, attribute)
+		case COLON:
+			if isPseudoElement(p.peekToken) || p.peekToken.Type == COLON {
+				name, ok := p.parsePseudoElement()
+				if !ok {
+					return selector
+				}
+				selector.PseudoElement = name
+				break
+			}
+			pseudo, ok := p.parsePseudoClass()
+			if !ok {
+				return selector
+			}
+			selector.PseudoClasses = append(selector.PseudoClasses, pseudo)
+		case GREATER, PLUS, TILDE:
+			if empty {
+				// a combinator must follow a compound
+				p.tokenError(IDENTIFIER)
+				p.nextToken()
+				return selector
+			}
+			selector = combine(selector, Combinator(p.curToken.Type))
+			empty = true
+			p.nextToken()
 			continue
 		default:
 			p.tokenError(IDENTIFIER)
@@ -151,11 +353,276 @@ func (p *Parser) parseSelector() Selector {
 			p.nextToken()
 			return selector
 		}
+		empty = false
+	}
+
+	if empty && selector.Relative != nil {
+		// a combinator must be followed by a compound
+		p.tokenError(IDENTIFIER)
 	}
 
 	return selector
 }
 
+// parseAttributeSelector parses selectors like `[attr]`, `[attr^="value"]` or `[attr=value i]`.
+func (p *Parser) parseAttributeSelector() (AttributeSelector, bool) {
+	attribute := AttributeSelector{}
+
+	// skip LBRACKET
+	p.nextToken()
+	if p.curToken.Type != IDENTIFIER {
+		p.tokenError(IDENTIFIER)
+		p.nextToken()
+		return attribute, false
+	}
+	attribute.Name = p.curToken.Litteral
+	p.nextToken()
+
+	if p.curToken.Type != RBRACKET {
+		switch p.curToken.Type {
+		case EQUAL:
+			attribute.Operator = Equals
+		case TILDE, PIPE, CARET, DOLLAR, STAR:
+			// the operator is made of two tokens, without whitespace between them
+			if p.peekToken.Type != EQUAL || p.peekSpace {
+				p.tokenError(EQUAL)
+				p.nextToken()
+				return attribute, false
+			}
+			attribute.Operator = AttributeOperator(p.curToken.Litteral + "=")
+			p.nextToken()
+		default:
+			p.tokenError(EQUAL)
+			p.nextToken()
+			return attribute, false
+		}
+		p.nextToken()
+
+		if p.curToken.Type != IDENTIFIER && p.curToken.Type != STRING {
+			p.tokenError(STRING)
+			p.nextToken()
+			return attribute, false
+		}
+		attribute.Value = p.curToken.Litteral
+		p.nextToken()
+
+		// case-sensitivity flag
+		if p.curToken.Type == IDENTIFIER {
+			switch strings.ToLower(p.curToken.Litteral) {
+			case "i":
+				attribute.CaseInsensitive = true
+			case "s":
+			default:
+				p.tokenError(RBRACKET)
+				p.nextToken()
+				return attribute, false
+			}
+			p.nextToken()
+		}
+	}
+
+	if p.curToken.Type != RBRACKET {
+		p.tokenError(RBRACKET)
+		p.nextToken()
+		return attribute, false
+	}
+	p.nextToken()
+
+	return attribute, true
+}
+
+// pseudoElements are the supported pseudo-elements.
+var pseudoElements = map[string]bool{
+	"before": true,
+	"after":  true,
+}
+
+// isPseudoElement reports whether tok is a pseudo-element name that may be written
+// with a single colon, like `:before`, for compatibility with CSS 2.
+func isPseudoElement(tok CSSToken) bool {
+	return tok.Type == IDENTIFIER && pseudoElements[strings.ToLower(tok.Litteral)]
+}
+
+// parsePseudoElement parses pseudo-elements like `::before`, and returns their name.
+func (p *Parser) parsePseudoElement() (string, bool) {
+	// skip the colons
+	p.nextToken()
+	if p.curToken.Type == COLON && !p.curSpace {
+		p.nextToken()
+	}
+	if p.curToken.Type != IDENTIFIER || p.curSpace {
+		p.tokenError(IDENTIFIER)
+		p.nextToken()
+		return "", false
+	}
+
+	name := strings.ToLower(p.curToken.Litteral)
+	if !pseudoElements[name] {
+		p.errors = append(p.errors, fmt.Sprintf("unknown pseudo-element ::%s", name))
+		p.nextToken()
+		return "", false
+	}
+	p.nextToken()
+	return name, true
+}
+
+// simplePseudoClasses are the pseudo-classes without arguments.
+var simplePseudoClasses = map[string]bool{
+	"first-child":   true,
+	"last-child":    true,
+	"only-child":    true,
+	"first-of-type": true,
+	"last-of-type":  true,
+	"only-of-type":  true,
+	"root":          true,
+	"empty":         true,
+}
+
+// parsePseudoClass parses pseudo-classes like `:first-child`, `:nth-child(2n+1 of .item)` or `:not(p, .note)`.
+func (p *Parser) parsePseudoClass() (PseudoClass, bool) {
+	pseudo := PseudoClass{}
+
+	// skip COLON
+	p.nextToken()
+	if p.curToken.Type != IDENTIFIER || p.curSpace {
+		p.tokenError(IDENTIFIER)
+		p.nextToken()
+		return pseudo, false
+	}
+	pseudo.Name = strings.ToLower(p.curToken.Litteral)
+	functional := p.peekToken.Type == LPARENTHESIS && !p.peekSpace
+	p.nextToken()
+
+	if !functional {
+		if !simplePseudoClasses[pseudo.Name] {
+			p.errors = append(p.errors, fmt.Sprintf("unknown pseudo-class :%s", pseudo.Name))
+			return pseudo, false
+		}
+		return pseudo, true
+	}
+
+	// skip LPARENTHESIS
+	p.nextToken()
+	errors := len(p.errors)
+	switch pseudo.Name {
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		var ok bool
+		if pseudo.A, pseudo.B, ok = p.parseNth(); !ok {
+			return pseudo, false
+		}
+		if isKeyword(p.curToken, "of") && (pseudo.Name == "nth-child" || pseudo.Name == "nth-last-child") {
+			p.nextToken()
+			pseudo.Selectors = p.parseSelectorList()
+		}
+	case "not", "is", "where":
+		pseudo.Selectors = p.parseSelectorList()
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("unknown pseudo-class :%s()", pseudo.Name))
+		return pseudo, false
+	}
+	if len(p.errors) > errors {
+		return pseudo, false
+	}
+
+	if p.curToken.Type != RPARENTHESIS {
+		p.tokenError(RPARENTHESIS)
+		return pseudo, false
+	}
+	p.nextToken()
+
+	return pseudo, true
+}
+
+// parseSelectorList parses the comma separated selectors given to a pseudo-class.
+func (p *Parser) parseSelectorList() []Selector {
+	var selectors []Selector
+	for p.curToken.Type != RPARENTHESIS && p.curToken.Type != LBRACE && p.curToken.Type != EOF {
+		selectors = append(selectors, p.parseSelector())
+		if p.curToken.Type == COMMA {
+			p.nextToken()
+		}
+	}
+	if len(selectors) == 0 {
+		p.tokenError(IDENTIFIER)
+	}
+	return selectors
+}
+
+// parseNth parses the An+B argument of the :nth-* pseudo-classes, like `2n+1`, `-n + 3` or `odd`.
+func (p *Parser) parseNth() (a, b int, ok bool) {
+	// The lexer splits An+B in unusual ways, like "2" "n-1" or "-n" "+" "3",
+	// so the tokens are joined back before being parsed.
+	var text string
+	for p.curToken.Type != RPARENTHESIS && p.curToken.Type != EOF && !isKeyword(p.curToken, "of") {
+		text += p.curToken.Litteral
+		p.nextToken()
+	}
+
+	a, b, ok = parseAnB(text)
+	if !ok {
+		p.errors = append(p.errors, fmt.Sprintf("bad An+B expression %q", text))
+	}
+	return a, b, ok
+}
+
+// parseAnB parses An+B expressions without whitespace.
+// See https://www.w3.org/TR/css-syntax-3/#anb-microsyntax
+func parseAnB(text string) (a, b int, ok bool) {
+	text = strings.ToLower(text)
+	switch text {
+	case "odd":
+		return 2, 1, true
+	case "even":
+		return 2, 0, true
+	}
+
+	i := strings.IndexByte(text, 'n')
+	if i < 0 {
+		b, err := strconv.Atoi(text)
+		return 0, b, err == nil
+	}
+
+	switch text[:i] {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(text[:i]); err != nil {
+			return 0, 0, false
+		}
+	}
+
+	rest := text[i+1:]
+	if rest == "" {
+		return a, 0, true
+	}
+	if rest[0] != '+' && rest[0] != '-' {
+		return 0, 0, false
+	}
+	b, err := strconv.Atoi(rest)
+	return a, b, err == nil
+}
+
+// isKeyword reports whether a token is the given identifier, ignoring case.
+func isKeyword(tok CSSToken, keyword string) bool {
+	return tok.Type == IDENTIFIER && strings.EqualFold(tok.Litteral, keyword)
+}
+
+// combine starts a new compound selector, related to the previous one by a combinator.
+func combine(relative Selector, combinator Combinator) Selector {
+	return Selector{
+		Classes:    []string{},
+		Combinator: combinator,
+		Relative:   &relative,
+	}
+}
+
+func isCombinator(t CSSTokenType) bool {
+	return t == GREATER || t == PLUS || t == TILDE
+}
+
 func (p *Parser) parseDeclaration() Declaration {
 	d := Declaration{}
 
@@ -169,13 +636,43 @@ func (p *Parser) parseDeclaration() Declaration {
 	p.nextToken()
 
 	if p.curToken.Type != COLON {
+		// a declaration without value, like `--brand;`, is ignored
 		p.tokenError(COLON)
-		return d
+		p.skipDeclaration()
+		return Declaration{}
 	}
 	p.nextToken()
 
-	d.Value = p.parseValue()
+	tokens := p.parseRawTokens()
+	if IsCustomProperty(d.Name) || hasVar(tokens) {
+		// the value is parsed once its variables are substituted, see Substitute
+		d.Value = Value{Raw: &RawValue{Tokens: tokens}}
+	} else {
+		value, errors := parseValueTokens(tokens)
+		if len(errors) > 0 {
+			// a declaration with an invalid value, like `calc(1px + 2)`, is ignored
+			p.errors = append(p.errors, errors...)
+			p.skipDeclaration()
+			return Declaration{}
+		}
+		d.Value = value
+	}
+	if p.curToken.Type == BANG {
+		if p.peekToken.Type != IDENTIFIER || strings.ToLower(p.peekToken.Litteral) != "important" {
+			// only !important can follow a value, the declaration is ignored
+			p.tokenError(IDENTIFIER)
+			p.skipDeclaration()
+			return Declaration{}
+		}
+		d.Important = true
+		p.nextToken()
+		p.nextToken()
+	}
 
+	// the last declaration of a list or of a block may omit its semicolon
+	if p.curToken.Type == EOF || p.curToken.Type == RBRACE {
+		return d
+	}
 	if p.curToken.Type != SEMICOLON {
 		p.tokenError(SEMICOLON)
 		return d
@@ -185,29 +682,135 @@ func (p *Parser) parseDeclaration() Declaration {
 	return d
 }
 
+// skipDeclaration skips the rest of an invalid declaration, up to the next one.
+func (p *Parser) skipDeclaration() {
+	for p.curToken.Type != SEMICOLON && p.curToken.Type != RBRACE && p.curToken.Type != EOF {
+		p.nextToken()
+	}
+	if p.curToken.Type == SEMICOLON {
+		p.nextToken()
+	}
+}
+
+// parseValue parses the value of a declaration, up to the semicolon ending it.
+// A value made of several components, like `1px solid red`, is returned as a list.
 func (p *Parser) parseValue() Value {
+	var groups []Value
+	for {
+		groups = append(groups, p.parseSpaceList())
+		if p.curToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+
+	if len(groups) == 1 {
+		return groups[0]
+	}
+	// comma separated list, like `"Go Mono", monospace`
+	return Value{List: &ValueList{Values: groups, Comma: true}}
+}
+
+// parseSpaceList parses the space separated components of a value, up to a comma
+// or the end of the value.
+func (p *Parser) parseSpaceList() Value {
+	var values []Value
+	for !isValueEnd(p.curToken.Type) {
+		v, ok := p.parseComponent()
+		if !ok {
+			break
+		}
+		values = append(values, v)
+	}
+
+	switch len(values) {
+	case 0:
+		return Value{}
+	case 1:
+		return values[0]
+	}
+	return Value{List: &ValueList{Values: values}}
+}
+
+func isValueEnd(t CSSTokenType) bool {
+	return t == SEMICOLON || t == RBRACE || t == RPARENTHESIS || t == COMMA || t == BANG || t == EOF
+}
+
+// parseComponent parses a single component of a value: a keyword, a string,
+// a length, a color, a math expression or a function.
+func (p *Parser) parseComponent() (Value, bool) {
 	v := Value{}
+	switch p.curToken.Type {
+	case IDENTIFIER:
+		if p.peekToken.Type == LPARENTHESIS && !p.peekSpace && isMathFunction(p.curToken.Litteral) {
+			math, ok := p.parseMathFunction()
+			if !ok {
+				return v, false
+			}
+			return Value{Math: math}, true
+		}
+		if p.peekToken.Type == LPARENTHESIS && !p.peekSpace {
+			v.Function = p.parseFunction()
+			if isColorFunction(v.Function.Name) {
+				color, ok := colorFunction(v.Function)
+				if !ok {
+					p.errors = append(p.errors, fmt.Sprintf("invalid color: %s()", v.Function.Name))
+					return v, false
+				}
+				return Value{Color: color}, true
+			}
+			return v, true
+		}
+		v.Keyword = p.curToken.Litteral
+		p.nextToken()
+	case STRING:
+		v.Text = p.curToken.Litteral
+		p.nextToken()
+	case URL:
+		// an unquoted url() is the same value as a quoted one
+		v.Function = &Function{Name: "url", Args: []Value{{Text: p.curToken.Litteral}}}
+		p.nextToken()
+	case NUMBER:
+		v.Length = p.parseLength()
+	case HASH:
+		v.Color = p.parseColor()
+	case SLASH:
+		// the separator of `font: 12px/1.5 serif`, kept as a keyword
+		v.Keyword = SLASH
+		p.nextToken()
+	case RBRACE, EOF:
+		// the end of the block or of the list, the value ends without semicolon
+		return v, false
+	default:
+		p.tokenError(SEMICOLON)
+		return v, false
+	}
+	return v, true
+}
 
-	if p.peekToken.Type == SEMICOLON || p.peekToken.Type == EOF {
-		// Value made of one token
-		if p.curToken.Type == IDENTIFIER {
-			v.Keyword = p.curToken.Litteral
+// parseFunction parses functional notations like `attr(title)` or `counter(item, upper-roman)`.
+func (p *Parser) parseFunction() *Function {
+	f := &Function{Name: strings.ToLower(p.curToken.Litteral)}
+
+	// skip the name and LPARENTHESIS
+	p.nextToken()
+	p.nextToken()
+
+	for {
+		if p.curToken.Type == RPARENTHESIS {
 			p.nextToken()
-		} else if p.curToken.Type == NUMBER {
-			v.Length = p.parseLength()
+			return f
 		}
-	} else {
-		// Value made of two or more tokens
-		if p.curToken.Type == HASH {
-			v.Color = p.parseColor()
+		f.Args = append(f.Args, p.parseSpaceList())
+		if p.curToken.Type == COMMA {
+			p.nextToken()
+			continue
 		}
-
-		if p.curToken.Type == NUMBER {
-			v.Length = p.parseLength()
+		if p.curToken.Type != RPARENTHESIS {
+			p.tokenError(RPARENTHESIS)
+			return f
 		}
 	}
-
-	return v
 }
 
 func (p *Parser) parseLength() Length {
@@ -218,62 +821,59 @@ func (p *Parser) parseLength() Length {
 	}
 
 	value := p.curToken.Litteral
-	f, err := strconv.ParseFloat(value, 32)
+	f, err := strconv.ParseFloat(value, 64)
 	if err != nil {
 		f = 0
 	}
 	length.Quantity = f
 
-	if p.peekToken.Type == SEMICOLON {
-		return length
-	}
-	if p.peekToken.Type != IDENTIFIER {
-		panic("bad number, expected IDENTIFIER or SEMICOLON(;) after NUMBER")
+	// the unit follows the number, without whitespace: `12 px` is invalid
+	switch {
+	case p.peekToken.Type == PERCENT && !p.peekSpace:
+		p.nextToken()
+		length.Unit = Percent
+	case p.peekToken.Type == IDENTIFIER && !p.peekSpace:
+		p.nextToken()
+		length.Unit = parseUnit(p.curToken.Litteral)
+	case p.peekToken.Type == IDENTIFIER && isLengthUnit(parseUnit(p.peekToken.Litteral)):
+		p.errors = append(p.errors, fmt.Sprintf("unexpected whitespace before unit %s", p.peekToken.Litteral))
 	}
-
 	p.nextToken()
-	t := p.curToken.Litteral
-
-	var unit Unit
-	switch t {
-	case "px":
-		unit = Px
-	case "em":
-		unit = Em
-	case "%":
-		unit = Percent
-	}
 
-	length.Unit = unit
+	return length
+}
 
-	p.nextToken()
+func isLengthUnit(u Unit) bool {
+	return u.Absolute() || u.FontRelative() || u.ViewportRelative()
+}
 
-	return length
+// parseUnit returns the unit of a dimension, like "px" in 12px.
+// Units are case-insensitive, and the ones without constant, like "deg", are kept lowercase.
+func parseUnit(s string) Unit {
+	return Unit(strings.ToLower(s))
 }
 
+// parseColor parses a hexadecimal color: #rgb, #rgba, #rrggbb or #rrggbbaa.
 func (p *Parser) parseColor() Color {
-	if p.curToken.Type != HASH || (p.peekToken.Type != IDENTIFIER && p.peekToken.Type != NUMBER) {
-		panic("bad color")
+	if p.curToken.Type != HASH || (p.peekToken.Type != IDENTIFIER && p.peekToken.Type != NUMBER) || p.peekSpace {
+		p.tokenError(HASH)
+		p.nextToken()
+		return Color{}
 	}
+	p.nextToken()
 
-	text := p.peekToken.Litteral
-
-	color := Color{}
-	if len(text) == 3 {
-		color.A = 255
-		color.R = hexToInt(string(text[0]) + string(text[0]))
-		color.G = hexToInt(string(text[1]) + string(text[1]))
-		color.B = hexToInt(string(text[2]) + string(text[2]))
-	} else if len(text) == 6 {
-		color.A = 255
-		color.R = hexToInt(text[0:2])
-		color.G = hexToInt(text[2:4])
-		color.B = hexToInt(text[4:6])
+	// digits and letters are read as separate tokens: #1a2b3c is 1 then a2b3c
+	text := p.curToken.Litteral
+	for (p.peekToken.Type == IDENTIFIER || p.peekToken.Type == NUMBER) && !p.peekSpace {
+		p.nextToken()
+		text += p.curToken.Litteral
 	}
-
-	p.nextToken()
 	p.nextToken()
 
+	color, ok := hexColor(text)
+	if !ok {
+		p.errors = append(p.errors, fmt.Sprintf("invalid color: #%s", text))
+	}
 	return color
 }
 
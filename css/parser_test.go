@@ -1,6 +1,7 @@
 package css
 
 import (
+	"math"
 	"strings"
 	"testing"
 )
@@ -15,6 +16,9 @@ var SelectorTests = []struct {
 	{"tag", Selector{TagName: "tag"}, false},
 	{"..", Selector{}, true},
 	{"#/", Selector{}, true},
+	{"*.class", Selector{TagName: "*", Classes: []string{"class"}}, false},
+	{".class*", Selector{}, true},
+	{"p*", Selector{}, true},
 }
 
 func TestSelector(t *testing.T) {
@@ -23,6 +27,12 @@ func TestSelector(t *testing.T) {
 
 		selector := p.parseSelector()
 
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Fatalf("%s - expected error: %v, got: %v", tt.input, tt.isErr, p.Errors())
+		}
+		if tt.isErr {
+			continue
+		}
 		actual := selector
 		if actual.ID != tt.expected.ID {
 			t.Fatalf("%s - expected: %v actual:  %v", tt.input, tt.expected, actual)
@@ -35,6 +45,235 @@ func TestSelector(t *testing.T) {
 		}
 	}
 }
+
+var CombinatorTests = []struct {
+	input    string
+	expected Selector
+	isErr    bool
+}{
+	{"nav a", Selector{TagName: "a", Combinator: Descendant, Relative: &Selector{TagName: "nav"}}, false},
+	{"ul > li", Selector{TagName: "li", Combinator: Child, Relative: &Selector{TagName: "ul"}}, false},
+	{"ul>li", Selector{TagName: "li", Combinator: Child, Relative: &Selector{TagName: "ul"}}, false},
+	{"h1 + p.note", Selector{TagName: "p", Classes: []string{"note"}, Combinator: NextSibling, Relative: &Selector{TagName: "h1"}}, false},
+	{"h1 ~ p", Selector{TagName: "p", Combinator: SubsequentSibling, Relative: &Selector{TagName: "h1"}}, false},
+	{
+		"#menu .item > a",
+		Selector{TagName: "a", Combinator: Child, Relative: &Selector{
+			Classes: []string{"item"}, Combinator: Descendant, Relative: &Selector{ID: "menu"},
+		}},
+		false,
+	},
+	{"> a", Selector{}, true},
+	{"a >", Selector{}, true},
+}
+
+func TestSelector_combinators(t *testing.T) {
+	for _, tt := range CombinatorTests {
+		p := NewParser(strings.NewReader(tt.input))
+
+		selector := p.parseSelector()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Fatalf("%s - expected error: %v, got: %v", tt.input, tt.isErr, p.Errors())
+		}
+		if tt.isErr {
+			continue
+		}
+		if selector.String() != tt.expected.String() {
+			t.Fatalf("%s - expected: %v actual:  %v", tt.input, tt.expected, selector)
+		}
+	}
+}
+
+var AttributeSelectorTests = []struct {
+	input    string
+	expected []AttributeSelector
+	isErr    bool
+}{
+	{"[disabled]", []AttributeSelector{{Name: "disabled"}}, false},
+	{"input[type=checkbox]", []AttributeSelector{{Name: "type", Operator: Equals, Value: "checkbox"}}, false},
+	{`a[href^="https"]`, []AttributeSelector{{Name: "href", Operator: PrefixMatch, Value: "https"}}, false},
+	{`a[href$='.pdf']`, []AttributeSelector{{Name: "href", Operator: SuffixMatch, Value: ".pdf"}}, false},
+	{`a[href*="example"]`, []AttributeSelector{{Name: "href", Operator: Substring, Value: "example"}}, false},
+	{"[lang|=en]", []AttributeSelector{{Name: "lang", Operator: DashMatch, Value: "en"}}, false},
+	{"[data-x~=y]", []AttributeSelector{{Name: "data-x", Operator: Includes, Value: "y"}}, false},
+	{"[ type = Checkbox i ]", []AttributeSelector{{Name: "type", Operator: Equals, Value: "Checkbox", CaseInsensitive: true}}, false},
+	{"[a][b=c]", []AttributeSelector{{Name: "a"}, {Name: "b", Operator: Equals, Value: "c"}}, false},
+	{`[title="a \"quote\""]`, []AttributeSelector{{Name: "title", Operator: Equals, Value: `a "quote"`}}, false},
+	{"[href^ =x]", nil, true},
+	{"[href=]", nil, true},
+	{"[href=x y]", nil, true},
+	{"[=x]", nil, true},
+}
+
+func TestSelector_attributes(t *testing.T) {
+	for _, tt := range AttributeSelectorTests {
+		p := NewParser(strings.NewReader(tt.input))
+
+		selector := p.parseSelector()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Fatalf("%s - expected error: %v, got: %v", tt.input, tt.isErr, p.Errors())
+		}
+		if tt.isErr {
+			continue
+		}
+		if len(selector.Attributes) != len(tt.expected) {
+			t.Fatalf("%s - expected: %v actual:  %v", tt.input, tt.expected, selector.Attributes)
+		}
+		for i, a := range selector.Attributes {
+			if a != tt.expected[i] {
+				t.Fatalf("%s - expected: %v actual:  %v", tt.input, tt.expected[i], a)
+			}
+		}
+	}
+}
+
+var PseudoClassTests = []struct {
+	input    string
+	expected string
+	isErr    bool
+}{
+	{"li:first-child", ":first-child", false},
+	{":ROOT", ":root", false},
+	{"p:nth-child(2n+1)", ":nth-child(2n+1)", false},
+	{"p:nth-child( -n + 3 )", ":nth-child(-1n+3)", false},
+	{"p:nth-child(2n-1)", ":nth-child(2n-1)", false},
+	{"p:nth-child(odd)", ":nth-child(2n+1)", false},
+	{"p:nth-last-child(even)", ":nth-last-child(2n+0)", false},
+	{"p:nth-of-type(3)", ":nth-of-type(0n+3)", false},
+	{"p:nth-child(n of .item)", ":nth-child(1n+0 of [Selector (CLASSES: ([item]))])", false},
+	{"p:not(.a, #b)", ":not([Selector (CLASSES: ([a])) Selector (ID: b)])", false},
+	{"p:is(ul > li)", `:is([Selector (TAG: ul) ">" Selector (TAG: li)])`, false},
+	{"p:unknown", "", true},
+	{"p:nth-child(2x)", "", true},
+	{"p:not()", "", true},
+	{"p:not(.a", "", true},
+}
+
+func TestSelector_pseudoClasses(t *testing.T) {
+	for _, tt := range PseudoClassTests {
+		p := NewParser(strings.NewReader(tt.input))
+
+		selector := p.parseSelector()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Fatalf("%s - expected error: %v, got: %v", tt.input, tt.isErr, p.Errors())
+		}
+		if tt.isErr {
+			continue
+		}
+		if len(selector.PseudoClasses) != 1 {
+			t.Fatalf("%s - expected 1 pseudo-class, got %v", tt.input, selector.PseudoClasses)
+		}
+		if actual := selector.PseudoClasses[0].String(); actual != tt.expected {
+			t.Fatalf("%s - expected: %s actual:  %s", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseStylesheet_invalidSelector(t *testing.T) {
+	p := NewParser(strings.NewReader("a:hover { color: red; } a { color: blue; }"))
+
+	stylesheet := p.ParseStylesheet()
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got %v", p.Errors())
+	}
+	if len(stylesheet.Rules) != 1 || stylesheet.Rules[0].Declarations[0].Value.Keyword != "blue" {
+		t.Fatalf("the rule with an invalid selector should be ignored, got %v", stylesheet)
+	}
+}
+
+func TestParseStylesheet_lastSemicolon(t *testing.T) {
+	p := NewParser(strings.NewReader("a { color: blue } b { margin: 1px; width: calc(1px + 2px) !important }"))
+
+	stylesheet := p.ParseStylesheet()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("the last declaration of a block may omit its semicolon, got %v", p.Errors())
+	}
+	if len(stylesheet.Rules) != 2 || len(stylesheet.Rules[1].Declarations) != 2 || !stylesheet.Rules[1].Declarations[1].Important {
+		t.Fatalf("expected 2 rules with all their declarations, got %v", stylesheet)
+	}
+}
+
+func TestSpecificity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Specificity
+	}{
+		{"*", Specificity{}},
+		{"div", Specificity{C: 1}},
+		{"blockquote", Specificity{C: 1}},
+		{"#a", Specificity{A: 1}},
+		{"#abcdef", Specificity{A: 1}},
+		{"*.a", Specificity{B: 1}},
+		{"p.a.b[c]", Specificity{B: 3, C: 1}},
+		{"#menu ul > li.item + a", Specificity{A: 1, B: 1, C: 3}},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input))
+		selector := p.parseSelector()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s - %v", tt.input, p.Errors())
+		}
+		if actual := selector.Specificity(); actual != tt.expected {
+			t.Errorf("%s - expected: %v actual: %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestSpecificity_pseudoClasses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Specificity
+	}{
+		{":first-child", Specificity{B: 1}},
+		{":where(.a, .b)", Specificity{}},
+		{":is(.a, [b][c])", Specificity{B: 2}},
+		{":not(.a.b)", Specificity{B: 2}},
+		{":nth-child(2n of .a.b)", Specificity{B: 3}},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input))
+		selector := p.parseSelector()
+		if actual := selector.Specificity(); actual != tt.expected {
+			t.Errorf("%s - expected: %v actual: %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestSelector_pseudoElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		isErr    bool
+	}{
+		{"p::before", "before", false},
+		{"p::AFTER", "after", false},
+		{"p:after", "after", false},
+		{"p::before.x", "", true},
+		{"p::marker", "", true},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input))
+
+		selector := p.parseSelector()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Fatalf("%s - expected error: %v, got: %v", tt.input, tt.isErr, p.Errors())
+		}
+		if tt.isErr {
+			continue
+		}
+		if selector.TagName != "p" || selector.PseudoElement != tt.expected {
+			t.Errorf("%s - expected: p::%s actual: %v", tt.input, tt.expected, selector)
+		}
+		if actual := selector.Specificity(); actual != (Specificity{C: 2}) {
+			t.Errorf("%s - expected specificity: {0 0 2} actual: %v", tt.input, actual)
+		}
+	}
+}
+
 func TestSelectors(t *testing.T) {
 	p := NewParser(strings.NewReader("#id, .class, tag"))
 
@@ -73,7 +312,7 @@ func TestColorDeclaration(t *testing.T) {
 }
 
 func TestLengthDeclaration(t *testing.T) {
-	p := NewParser(strings.NewReader("size: 50.5 px;"))
+	p := NewParser(strings.NewReader("size: 50.5px;"))
 
 	declaration := p.parseDeclaration()
 	if declaration.Name != "size" {
@@ -86,6 +325,169 @@ func TestLengthDeclaration(t *testing.T) {
 	}
 }
 
+func TestLengthDeclaration_units(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Length
+	}{
+		{"width: 50%", Length{Quantity: 50, Unit: Percent}},
+		{"width: 1.5EM", Length{Quantity: 1.5, Unit: Em}},
+		{"width: 2rem", Length{Quantity: 2, Unit: Rem}},
+		{"width: 10vw", Length{Quantity: 10, Unit: Vw}},
+		{"width: 10vmin", Length{Quantity: 10, Unit: Vmin}},
+		{"width: 12pt", Length{Quantity: 12, Unit: Pt}},
+		{"width: 1pc", Length{Quantity: 1, Unit: Pc}},
+		{"width: 1in", Length{Quantity: 1, Unit: In}},
+		{"width: 2.54cm", Length{Quantity: 2.54, Unit: Cm}},
+		{"width: 4Q", Length{Quantity: 4, Unit: Q}},
+		{"margin-left: -8px", Length{Quantity: -8, Unit: Px}},
+		{"margin-left: -.5em", Length{Quantity: -0.5, Unit: Em}},
+		{"width: 21.44px", Length{Quantity: 21.44, Unit: Px}},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input))
+
+		declarations := p.ParseDeclarations()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s - %v", tt.input, p.Errors())
+		}
+		if actual := declarations[0].Value; actual != (Value{Length: tt.expected}) {
+			t.Errorf("%s - expected: %v actual: %v", tt.input, tt.expected, actual.Length)
+		}
+	}
+}
+
+func TestLengthDeclaration_spaceBeforeUnit(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"width: 12 px", false},
+		{"width: 1.5 EM", false},
+		{"height: 50 %", false},
+		{"margin: 0 auto", true},
+	}
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader(tt.input + "; padding: 2px"))
+
+		declarations := p.ParseDeclarations()
+		if isErr := len(p.Errors()) > 0; isErr == tt.valid {
+			t.Errorf("%s - expected valid: %v, got errors: %v", tt.input, tt.valid, p.Errors())
+		}
+		if valid := len(declarations) == 2; valid != tt.valid || declarations[len(declarations)-1].Name != "padding" {
+			t.Errorf("%s - expected valid: %v, got declarations: %v", tt.input, tt.valid, declarations)
+		}
+	}
+}
+
+func TestLength_ToPx(t *testing.T) {
+	ctx := LengthContext{FontSize: 20, RootFontSize: 16, PercentBase: 200, ViewportWidth: 800, ViewportHeight: 600}
+	tests := []struct {
+		length   Length
+		expected float64
+	}{
+		{Length{Quantity: 3, Unit: Px}, 3},
+		{Length{Quantity: 1.5, Unit: Em}, 30},
+		{Length{Quantity: 2, Unit: Rem}, 32},
+		{Length{Quantity: 2, Unit: Ch}, 20},
+		{Length{Quantity: 2, Unit: Ex}, 20},
+		{Length{Quantity: 25, Unit: Percent}, 50},
+		{Length{Quantity: 10, Unit: Vw}, 80},
+		{Length{Quantity: 10, Unit: Vh}, 60},
+		{Length{Quantity: 10, Unit: Vmin}, 60},
+		{Length{Quantity: 10, Unit: Vmax}, 80},
+		{Length{Quantity: 72, Unit: Pt}, 96},
+		{Length{Quantity: 6, Unit: Pc}, 96},
+		{Length{Quantity: 1, Unit: In}, 96},
+		{Length{Quantity: 2.54, Unit: Cm}, 96},
+		{Length{Quantity: 25.4, Unit: Mm}, 96},
+		{Length{Quantity: 101.6, Unit: Q}, 96},
+		{Length{Quantity: -1, Unit: In}, -96},
+		{Length{Quantity: 5}, 0},
+		{Length{Quantity: 90, Unit: "deg"}, 0},
+	}
+	for _, tt := range tests {
+		if actual := tt.length.ToPx(ctx); math.Abs(actual-tt.expected) > 1e-9 {
+			t.Errorf("%v%s - expected: %v actual: %v", tt.length.Quantity, tt.length.Unit, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseDeclarations(t *testing.T) {
+	p := NewParser(strings.NewReader("color: red; margin: 4px; line-height: 2"))
+
+	declarations := p.ParseDeclarations()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	expected := []Declaration{
+		{Name: "color", Value: Value{Keyword: "red"}},
+		{Name: "margin", Value: Value{Length: Length{Quantity: 4, Unit: Px}}},
+		{Name: "line-height", Value: Value{Length: Length{Quantity: 2}}},
+	}
+	if len(declarations) != len(expected) {
+		t.Fatalf("expected %d declarations, got %d", len(expected), len(declarations))
+	}
+	for i, d := range declarations {
+		if d != expected[i] {
+			t.Errorf("declarations[%d] - expected: %v actual: %v", i, expected[i], d)
+		}
+	}
+}
+
+func TestParseDeclarations_important(t *testing.T) {
+	p := NewParser(strings.NewReader("color: red !important; margin: 4px ! IMPORTANT; width: 2px !bad; height: 1px"))
+
+	declarations := p.ParseDeclarations()
+	if len(p.Errors()) == 0 {
+		t.Fatal("expected an error for !bad")
+	}
+
+	expected := []Declaration{
+		{Name: "color", Value: Value{Keyword: "red"}, Important: true},
+		{Name: "margin", Value: Value{Length: Length{Quantity: 4, Unit: Px}}, Important: true},
+		{Name: "height", Value: Value{Length: Length{Quantity: 1, Unit: Px}}},
+	}
+	if len(declarations) != len(expected) {
+		t.Fatalf("expected %d declarations, got %v", len(expected), declarations)
+	}
+	for i, d := range declarations {
+		if d != expected[i] {
+			t.Errorf("declarations[%d] - expected: %v actual: %v", i, expected[i], d)
+		}
+	}
+}
+
+func TestParseDeclarations_content(t *testing.T) {
+	p := NewParser(strings.NewReader(`content: "Chapter " counter(chapter, upper-roman) ": " attr(title)`))
+
+	declarations := p.ParseDeclarations()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+	if len(declarations) != 1 {
+		t.Fatalf("expected 1 declaration, got %d", len(declarations))
+	}
+
+	list := declarations[0].Value.List
+	if list == nil || list.Comma || len(list.Values) != 4 {
+		t.Fatalf("expected 4 space separated values, got %v", declarations[0].Value)
+	}
+	if list.Values[0].Text != "Chapter " || list.Values[2].Text != ": " {
+		t.Errorf("wrong strings: %v", list.Values)
+	}
+	counter := list.Values[1].Function
+	if counter == nil || counter.Name != "counter" || len(counter.Args) != 2 ||
+		counter.Args[0].Keyword != "chapter" || counter.Args[1].Keyword != "upper-roman" {
+		t.Errorf("wrong counter() function: %v", list.Values[1])
+	}
+	attr := list.Values[3].Function
+	if attr == nil || attr.Name != "attr" || len(attr.Args) != 1 || attr.Args[0].Keyword != "title" {
+		t.Errorf("wrong attr() function: %v", list.Values[3])
+	}
+}
+
 var ColorTests = []struct {
 	input    string
 	expected Color
@@ -96,6 +498,14 @@ var ColorTests = []struct {
 	{"#000000", Color{A: 255, R: 0, G: 0, B: 0}},
 	{"#DD0001", Color{A: 255, R: 221, G: 0, B: 1}},
 	{"#abc", Color{A: 255, R: 170, G: 187, B: 204}},
+	{"#1a2b3c", Color{A: 255, R: 0x1a, G: 0x2b, B: 0x3c}},
+	{"#123", Color{A: 255, R: 0x11, G: 0x22, B: 0x33}},
+	{"#f008", Color{A: 0x88, R: 255, G: 0, B: 0}},
+	{"#00ff0080", Color{A: 0x80, R: 0, G: 255, B: 0}},
+	{"#0000", Transparent},
+	{"#00000000", Transparent},
+	{"#12345", Color{}},
+	{"#ggg", Color{}},
 }
 
 func TestColor(t *testing.T) {
@@ -2,12 +2,32 @@ package css
 
 import (
 	"fmt"
+	"math"
 	"strings"
 )
 
 // Stylesheet represents a whole CSS file
 type Stylesheet struct {
 	Rules []Rule
+
+	// Imports are the @import rules, the stylesheets to load before the rules
+	Imports []Import
+
+	// FontFaces are the @font-face rules, the font files to load for their family
+	FontFaces []FontFace
+}
+
+// Merge returns a stylesheet holding the rules and the font faces of the given stylesheets, in order.
+// Nil stylesheets are skipped. The imports are not merged, they are expected to be loaded already.
+func Merge(sheets ...*Stylesheet) *Stylesheet {
+	merged := &Stylesheet{Rules: []Rule{}}
+	for _, s := range sheets {
+		if s != nil {
+			merged.Rules = append(merged.Rules, s.Rules...)
+			merged.FontFaces = append(merged.FontFaces, s.FontFaces...)
+		}
+	}
+	return merged
 }
 
 func (s Stylesheet) String() string {
@@ -18,10 +38,60 @@ func (s Stylesheet) String() string {
 	return r
 }
 
+// SetOrigin sets the origin of all the rules of the stylesheet.
+func (s *Stylesheet) SetOrigin(origin Origin) {
+	for i := range s.Rules {
+		s.Rules[i].Origin = origin
+	}
+}
+
 // Rule represents a CSS block
 type Rule struct {
 	Selectors    []Selector
 	Declarations []Declaration
+	Origin       Origin
+
+	// Conditions are the conditions of the @media and @supports rules containing the rule
+	Conditions
+}
+
+// Conditions are the conditions of the @media and @supports rules containing a rule,
+// which must all be true for the rule to apply.
+type Conditions struct {
+	Media    []MediaQueryList     // see MatchesMedia
+	Supports []*SupportsCondition // see MatchesSupports
+}
+
+// withMedia returns the conditions of a rule nested in an @media rule.
+func (c Conditions) withMedia(queries MediaQueryList) Conditions {
+	c.Media = append(c.Media[:len(c.Media):len(c.Media)], queries)
+	return c
+}
+
+// withSupports returns the conditions of a rule nested in an @supports rule.
+func (c Conditions) withSupports(condition *SupportsCondition) Conditions {
+	c.Supports = append(c.Supports[:len(c.Supports):len(c.Supports)], condition)
+	return c
+}
+
+// Origin tells where the rule of a stylesheet comes from.
+// See https://www.w3.org/TR/css-cascade-4/#cascading-origins
+type Origin int
+
+const (
+	Author    Origin = iota // the document and the -css stylesheet
+	User                    // the stylesheet chosen by the reader
+	UserAgent               // the default stylesheet of the browser
+)
+
+func (o Origin) String() string {
+	switch o {
+	case User:
+		return "user"
+	case UserAgent:
+		return "user-agent"
+	}
+	return "author"
 }
 
 func (s Rule) String() string {
@@ -36,11 +106,109 @@ func (s Rule) String() string {
 	return r
 }
 
-// Selector represents a CSS selector, present before each CSS block
+// Selector represents a CSS selector, present before each CSS block.
+// The fields describe the compound selector matching the element itself, like `a.link`.
+// In a complex selector like `nav > a.link`, the compounds on the left are chained
+// through Relative, and Combinator tells how they relate to this one.
 type Selector struct {
-	TagName string
-	ID      string
-	Classes []string
+	TagName       string
+	ID            string
+	Classes       []string
+	Attributes    []AttributeSelector
+	PseudoClasses []PseudoClass
+
+	// PseudoElement is the name of the pseudo-element the selector matches,
+	// like "before" for `p::before`. It is always on the last compound.
+	PseudoElement string
+
+	Combinator Combinator
+	Relative   *Selector
+}
+
+// Combinator describes the relation between two compound selectors.
+type Combinator string
+
+const (
+	NoCombinator      Combinator = ""
+	Descendant        Combinator = " "
+	Child             Combinator = ">"
+	NextSibling       Combinator = "+"
+	SubsequentSibling Combinator = "~"
+)
+
+// AttributeSelector matches an element attribute, like `[type=checkbox]` or `[lang|=en i]`.
+type AttributeSelector struct {
+	Name     string
+	Operator AttributeOperator
+	Value    string
+
+	// CaseInsensitive compares the value ignoring ASCII case, set with the `i` flag
+	CaseInsensitive bool
+}
+
+func (a AttributeSelector) String() string {
+	if a.Operator == Exists {
+		return fmt.Sprintf("[%s]", a.Name)
+	}
+	r := fmt.Sprintf("[%s%s%q", a.Name, a.Operator, a.Value)
+	if a.CaseInsensitive {
+		r += " i"
+	}
+	return r + "]"
+}
+
+// AttributeOperator tells how an attribute selector compares the attribute value.
+type AttributeOperator string
+
+const (
+	Exists      AttributeOperator = ""   // [attr]
+	Equals      AttributeOperator = "="  // [attr=value]
+	Includes    AttributeOperator = "~=" // [attr~=value], one of the whitespace separated words
+	DashMatch   AttributeOperator = "|=" // [attr|=value], value or starting with value-
+	PrefixMatch AttributeOperator = "^=" // [attr^=value]
+	SuffixMatch AttributeOperator = "$=" // [attr$=value]
+	Substring   AttributeOperator = "*=" // [attr*=value]
+)
+
+// PseudoClass matches an element by its position in the document, like `:first-child`,
+// or by other selectors, like `:not(.hidden)`.
+type PseudoClass struct {
+	Name string // lowercase name, without colon: "nth-child"
+
+	// A and B are the An+B formula of the :nth-* pseudo-classes
+	A, B int
+
+	// Selectors is the argument list of :not(), :is() and :where(),
+	// and the `of S` filter of :nth-child() and :nth-last-child()
+	Selectors []Selector
+}
+
+func (p PseudoClass) String() string {
+	switch {
+	case strings.HasPrefix(p.Name, "nth-") && len(p.Selectors) > 0:
+		return fmt.Sprintf(":%s(%dn%+d of %v)", p.Name, p.A, p.B, p.Selectors)
+	case strings.HasPrefix(p.Name, "nth-"):
+		return fmt.Sprintf(":%s(%dn%+d)", p.Name, p.A, p.B)
+	case len(p.Selectors) > 0:
+		return fmt.Sprintf(":%s(%v)", p.Name, p.Selectors)
+	}
+	return ":" + p.Name
+}
+
+// specificity returns the specificity the pseudo-class adds to its selector.
+func (p PseudoClass) specificity() Specificity {
+	switch p.Name {
+	case "where":
+		return Specificity{}
+	case "not", "is":
+		// the specificity of the most specific argument
+		return maxSpecificity(p.Selectors)
+	case "nth-child", "nth-last-child":
+		s := maxSpecificity(p.Selectors)
+		s.B++
+		return s
+	}
+	return Specificity{B: 1}
 }
 
 func (s Selector) String() string {
@@ -54,8 +222,20 @@ func (s Selector) String() string {
 	if len(s.Classes) > 0 {
 		parts = append(parts, fmt.Sprintf("CLASSES: ([%v])", strings.Join(s.Classes, ", ")))
 	}
+	if len(s.Attributes) > 0 {
+		parts = append(parts, fmt.Sprintf("ATTRIBUTES: (%v)", s.Attributes))
+	}
+	if len(s.PseudoClasses) > 0 {
+		parts = append(parts, fmt.Sprintf("PSEUDO-CLASSES: (%v)", s.PseudoClasses))
+	}
+	if s.PseudoElement != "" {
+		parts = append(parts, fmt.Sprintf("PSEUDO-ELEMENT: %s", s.PseudoElement))
+	}
 
 	r := fmt.Sprintf("Selector (%s)", strings.Join(parts, ", "))
+	if s.Relative != nil {
+		r = fmt.Sprintf("%v %q %s", *s.Relative, string(s.Combinator), r)
+	}
 	return r
 }
 
@@ -66,18 +246,62 @@ type Specificity struct {
 }
 
 // Specificity computes and returns the specificity of a selector.
+// A counts the ID selectors, B the class, attribute and pseudo-class selectors,
+// and C the type selectors and pseudo-elements. The universal selector `*` does not count.
+// See https://www.w3.org/TR/selectors-4/#specificity-rules
 func (s *Selector) Specificity() Specificity {
-	return Specificity{
-		A: len(s.ID),
-		B: len(s.Classes),
-		C: len(s.TagName),
+	specificity := Specificity{
+		B: len(s.Classes) + len(s.Attributes),
+	}
+	if s.ID != "" {
+		specificity.A++
+	}
+	if s.TagName != "" && s.TagName != "*" {
+		specificity.C++
+	}
+	if s.PseudoElement != "" {
+		specificity.C++
+	}
+	for _, p := range s.PseudoClasses {
+		specificity = specificity.add(p.specificity())
 	}
+	if s.Relative != nil {
+		specificity = specificity.add(s.Relative.Specificity())
+	}
+	return specificity
+}
+
+// Less reports whether s is less specific than o.
+func (s Specificity) Less(o Specificity) bool {
+	if s.A != o.A {
+		return s.A < o.A
+	}
+	if s.B != o.B {
+		return s.B < o.B
+	}
+	return s.C < o.C
+}
+
+func (s Specificity) add(o Specificity) Specificity {
+	return Specificity{A: s.A + o.A, B: s.B + o.B, C: s.C + o.C}
+}
+
+// maxSpecificity returns the specificity of the most specific selector of a list.
+func maxSpecificity(selectors []Selector) Specificity {
+	var max Specificity
+	for _, s := range selectors {
+		if spe := s.Specificity(); max.Less(spe) {
+			max = spe
+		}
+	}
+	return max
 }
 
 // Declaration represents a single CSS property
 type Declaration struct {
-	Name  string
-	Value Value
+	Name      string
+	Value     Value
+	Important bool // declared with !important
 }
 
 func (s Declaration) String() string {
@@ -85,6 +309,9 @@ func (s Declaration) String() string {
 	if s.Name != "" {
 		r += fmt.Sprint(s.Name)
 		r += fmt.Sprint(s.Value)
+		if s.Important {
+			r += " !important"
+		}
 	} else {
 		r += "No Name..."
 	}
@@ -93,17 +320,36 @@ func (s Declaration) String() string {
 
 // Value represents the possible value of a CSS declaration
 type Value struct {
-	Keyword string
-	Length  Length
-	Color   Color
+	Keyword  string
+	Length   Length
+	Color    Color
+	Text     string    // quoted string, without its quotes
+	Function *Function // functional notation, like attr(title)
+	Math     *MathExpr // math function, like calc(100% - 16px)
+	List     *ValueList
+	Raw      *RawValue // value of a custom property, or value using var()
+}
+
+// Function is a functional notation, like `attr(title)` or `counter(item, upper-roman)`.
+type Function struct {
+	Name string // lowercase name
+	Args []Value
+}
+
+// ValueList holds the components of a value made of several parts,
+// like `1px solid red` or `"Go Mono", monospace`.
+type ValueList struct {
+	Values []Value
+	Comma  bool // the values are separated by commas instead of whitespace
 }
 
-// ToPx is a Helper method needed by layout.go to get the actual pixel value
-func (v Value) ToPx() float64 {
-	if v.Length.Unit == Px {
-		return v.Length.Quantity
+// ToPx is a Helper method needed by layout.go to get the actual pixel value.
+// Relative lengths are resolved against ctx, keywords like auto are 0.
+func (v Value) ToPx(ctx LengthContext) float64 {
+	if v.Math != nil {
+		return v.Math.Eval(ctx)
 	}
-	return 0.0
+	return v.Length.ToPx(ctx)
 }
 
 // Length describes a unit of length in CSS
@@ -112,14 +358,103 @@ type Length struct {
 	Unit     Unit
 }
 
+// ToPx returns the length in pixels, resolving relative units against ctx.
+// Numbers without unit and unknown units are 0.
+// See https://www.w3.org/TR/css-values-4/#lengths
+func (l Length) ToPx(ctx LengthContext) float64 {
+	if px, ok := absoluteUnits[l.Unit]; ok {
+		return l.Quantity * px
+	}
+
+	switch l.Unit {
+	case Em:
+		return l.Quantity * ctx.FontSize
+	case Rem:
+		return l.Quantity * ctx.RootFontSize
+	case Ch, Ex:
+		// without the metrics of the font, both are half an em
+		return l.Quantity * ctx.FontSize / 2
+	case Percent:
+		return l.Quantity * ctx.PercentBase / 100
+	case Vw:
+		return l.Quantity * ctx.ViewportWidth / 100
+	case Vh:
+		return l.Quantity * ctx.ViewportHeight / 100
+	case Vmin:
+		return l.Quantity * math.Min(ctx.ViewportWidth, ctx.ViewportHeight) / 100
+	case Vmax:
+		return l.Quantity * math.Max(ctx.ViewportWidth, ctx.ViewportHeight) / 100
+	}
+	return 0.0
+}
+
+// LengthContext holds the sizes in pixels relative lengths are resolved against.
+type LengthContext struct {
+	FontSize     float64 // em, ch and ex
+	RootFontSize float64 // rem, the font size of the root element
+	PercentBase  float64 // %, usually the width of the containing block
+
+	// ViewportWidth and ViewportHeight are the size of the initial containing block,
+	// for vw, vh, vmin and vmax
+	ViewportWidth, ViewportHeight float64
+}
+
 type Unit string
 
 const (
 	Px      Unit = "px"
 	Em           = "em"
-	Percent      = "pc"
+	Percent      = "%"
+	Rem          = "rem"
+	Ch           = "ch"
+	Ex           = "ex"
+	Vw           = "vw"
+	Vh           = "vh"
+	Vmin         = "vmin"
+	Vmax         = "vmax"
+	Pt           = "pt"
+	Pc           = "pc"
+	In           = "in"
+	Cm           = "cm"
+	Mm           = "mm"
+	Q            = "q"
 )
 
+// absoluteUnits are the sizes in pixels of the absolute units, with 96 pixels per inch.
+var absoluteUnits = map[Unit]float64{
+	Px: 1,
+	Pt: 96.0 / 72,
+	Pc: 96.0 / 6,
+	In: 96,
+	Cm: 96 / 2.54,
+	Mm: 96 / 25.4,
+	Q:  96 / 101.6,
+}
+
+// Absolute reports whether lengths in the unit have a fixed size in pixels, like in and pt.
+func (u Unit) Absolute() bool {
+	_, ok := absoluteUnits[u]
+	return ok
+}
+
+// ViewportRelative reports whether lengths in the unit are relative to the size of the viewport, like vw.
+func (u Unit) ViewportRelative() bool {
+	switch u {
+	case Vw, Vh, Vmin, Vmax:
+		return true
+	}
+	return false
+}
+
+// FontRelative reports whether lengths in the unit are relative to a font size, like em and rem.
+func (u Unit) FontRelative() bool {
+	switch u {
+	case Em, Rem, Ch, Ex:
+		return true
+	}
+	return false
+}
+
 type Color struct {
 	Name       string
 	A, R, G, B int
@@ -0,0 +1,197 @@
+package css
+
+import "strings"
+
+// SupportsCondition is the condition of an @supports rule, like `(display: grid) and (not (width: 1px))`.
+// It tests if the engine supports declarations, or selectors with `selector(a > b)`.
+// See https://www.w3.org/TR/css-conditional-4/#at-supports
+type SupportsCondition struct {
+	Op         string // "and", "or" or "not", empty for a test
+	Conditions []*SupportsCondition
+
+	// Declaration is the declaration of a test like (display: grid), nil if its value is invalid
+	Declaration *Declaration
+	// Selector is the selector of a selector() test, nil if it is invalid
+	Selector *Selector
+}
+
+// MatchesSupports reports whether the conditions of the @supports rules containing the rule are all true.
+// supported tells which declarations the engine supports. A rule outside of @supports always matches.
+func (c Conditions) MatchesSupports(supported func(Declaration) bool) bool {
+	for _, condition := range c.Supports {
+		if !condition.Matches(supported) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether the condition is true, supported telling which declarations the engine supports.
+// Selectors are supported when they are valid. The tests the parser does not know, like (foo bar), are false.
+func (c *SupportsCondition) Matches(supported func(Declaration) bool) bool {
+	switch c.Op {
+	case "not":
+		return !c.Conditions[0].Matches(supported)
+	case "and":
+		for _, condition := range c.Conditions {
+			if !condition.Matches(supported) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, condition := range c.Conditions {
+			if condition.Matches(supported) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case c.Declaration != nil:
+		return supported(*c.Declaration)
+	case c.Selector != nil:
+		return true
+	}
+	return false
+}
+
+// parseSupportsCondition parses `not (test)`, or tests in parentheses combined with and,
+// or with or, but not both.
+func (p *Parser) parseSupportsCondition() (*SupportsCondition, bool) {
+	if isKeyword(p.curToken, "not") {
+		p.nextToken()
+		condition, ok := p.parseSupportsInParens()
+		return &SupportsCondition{Op: "not", Conditions: []*SupportsCondition{condition}}, ok
+	}
+
+	first, ok := p.parseSupportsInParens()
+	if !ok {
+		return nil, false
+	}
+	conditions := []*SupportsCondition{first}
+	op := ""
+	for isKeyword(p.curToken, "and") || isKeyword(p.curToken, "or") {
+		next := strings.ToLower(p.curToken.Litteral)
+		if op != "" && next != op {
+			return nil, false
+		}
+		op = next
+		p.nextToken()
+
+		condition, ok := p.parseSupportsInParens()
+		if !ok {
+			return nil, false
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if op == "" {
+		return first, true
+	}
+	return &SupportsCondition{Op: op, Conditions: conditions}, true
+}
+
+// parseSupportsInParens parses a condition in parentheses, a declaration like (display: grid),
+// or a selector() function. Other parentheses and functions are unknown tests, which are false.
+func (p *Parser) parseSupportsInParens() (*SupportsCondition, bool) {
+	switch {
+	case p.curToken.Type == LPARENTHESIS:
+		p.nextToken()
+		switch {
+		case p.curToken.Type == LPARENTHESIS || isKeyword(p.curToken, "not"):
+			condition, ok := p.parseSupportsCondition()
+			if !ok || p.curToken.Type != RPARENTHESIS {
+				return nil, false
+			}
+			p.nextToken()
+			return condition, true
+		case p.curToken.Type == IDENTIFIER && p.peekToken.Type == COLON:
+			return p.parseSupportsDeclaration()
+		}
+		_, ok := p.parseParenthesized()
+		return &SupportsCondition{}, ok
+
+	case p.curToken.Type == IDENTIFIER && p.peekToken.Type == LPARENTHESIS && !p.peekSpace:
+		selector := isKeyword(p.curToken, "selector")
+		// skip the name and LPARENTHESIS
+		p.nextToken()
+		p.nextToken()
+
+		tokens, ok := p.parseParenthesized()
+		if !ok {
+			return nil, false
+		}
+		if selector {
+			return &SupportsCondition{Selector: parseSelectorTokens(tokens)}, true
+		}
+		return &SupportsCondition{}, true
+	}
+	return nil, false
+}
+
+// parseSupportsDeclaration parses the declaration of a test, like `display: grid)`, up to its parenthesis.
+// The condition has no declaration if the value is invalid: the test is false.
+func (p *Parser) parseSupportsDeclaration() (*SupportsCondition, bool) {
+	name := p.curToken.Litteral
+	// skip the name and COLON
+	p.nextToken()
+	p.nextToken()
+
+	tokens, ok := p.parseParenthesized()
+	if !ok {
+		return nil, false
+	}
+	// the declaration may be !important, like in a rule
+	if n := len(tokens); n >= 2 && tokens[n-2].Type == BANG && isKeyword(tokens[n-1].CSSToken, "important") {
+		tokens = tokens[:n-2]
+	}
+
+	condition := &SupportsCondition{}
+	switch {
+	case IsCustomProperty(name) || hasVar(tokens):
+		condition.Declaration = &Declaration{Name: name, Value: Value{Raw: &RawValue{Tokens: tokens}}}
+	case len(tokens) > 0:
+		if value, errors := parseValueTokens(tokens); len(errors) == 0 {
+			condition.Declaration = &Declaration{Name: name, Value: value}
+		}
+	}
+	return condition, true
+}
+
+// parseParenthesized returns the tokens up to the parenthesis closing the one before the current token,
+// and skips them. It returns false if the parenthesis is not closed before the end of the prelude.
+func (p *Parser) parseParenthesized() ([]RawToken, bool) {
+	var tokens []RawToken
+	depth := 0
+	for p.curToken.Type != EOF {
+		switch p.curToken.Type {
+		case LPARENTHESIS:
+			depth++
+		case RPARENTHESIS:
+			if depth == 0 {
+				p.nextToken()
+				return tokens, true
+			}
+			depth--
+		case LBRACE, SEMICOLON:
+			if depth == 0 {
+				return nil, false
+			}
+		}
+		tokens = append(tokens, RawToken{CSSToken: p.curToken, Space: p.curSpace && len(tokens) > 0})
+		p.nextToken()
+	}
+	return nil, false
+}
+
+// parseSelectorTokens parses the selector of a selector() test, and returns nil if it is invalid.
+func parseSelectorTokens(tokens []RawToken) *Selector {
+	p := newParser(&tokenReader{tokens: tokens})
+	selector := p.parseSelector()
+	if len(p.errors) > 0 || p.curToken.Type != EOF {
+		return nil
+	}
+	return &selector
+}
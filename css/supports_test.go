@@ -0,0 +1,103 @@
+package css
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSupportsConditions(t *testing.T) {
+	// the engine of the test supports block, inline and none displays, and the width property
+	supported := func(d Declaration) bool {
+		switch d.Name {
+		case "display":
+			return d.Value.Keyword == "block" || d.Value.Keyword == "inline" || d.Value.Keyword == "none"
+		case "width":
+			return true
+		}
+		return false
+	}
+
+	tests := []struct {
+		condition string
+		expected  bool
+		isErr     bool
+	}{
+		{"(display: block)", true, false},
+		{"(display: grid)", false, false},
+		{"(display: block !important)", true, false},
+		{"(float: left)", false, false},
+		{"(width: calc(100% - 2px))", true, false},
+		{"(width: calc(1px + 2))", false, false},
+		{"(width: var(--gap))", true, false},
+		{"(--anything: [1] (2))", false, false},
+		{"(width:)", false, false},
+		{"not (display: grid)", true, false},
+		{"(display: grid) or (display: block)", true, false},
+		{"(display: grid) and (display: block)", false, false},
+		{"(display: none) and (width: 1px) and (display: inline)", true, false},
+		{"((display: grid) or (display: none)) and (not (float: left))", true, false},
+		{"selector(nav > a.link)", true, false},
+		{"selector(a::unknown)", false, false},
+		{"(unknown test)", false, false},
+		{"font-tech(color-COLRv1)", false, false},
+		{"not (unknown test)", true, false},
+
+		// invalid conditions drop the @supports rule
+		{"display: block", false, true},
+		{"(display: grid) and (display: block) or (width: 1px)", false, true},
+		{"(display: block", false, true},
+		{"", false, true},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(strings.NewReader("@supports " + tt.condition + " { a { color: red; } } b { color: red; }"))
+		stylesheet := p.ParseStylesheet()
+
+		if isErr := len(p.Errors()) > 0; isErr != tt.isErr {
+			t.Errorf("%s - expected error: %v, got: %v", tt.condition, tt.isErr, p.Errors())
+			continue
+		}
+		if tt.isErr {
+			// the following rules are kept
+			if n := len(stylesheet.Rules); n != 1 || stylesheet.Rules[0].Selectors[0].TagName != "b" {
+				t.Errorf("%s - expected the rule following @supports, got %v", tt.condition, stylesheet.Rules)
+			}
+			continue
+		}
+		if len(stylesheet.Rules) != 2 {
+			t.Errorf("%s - expected 2 rules, got %d", tt.condition, len(stylesheet.Rules))
+			continue
+		}
+		if actual := stylesheet.Rules[0].MatchesSupports(supported); actual != tt.expected {
+			t.Errorf("%s - expected %v, got %v", tt.condition, tt.expected, actual)
+		}
+		if !stylesheet.Rules[1].MatchesSupports(supported) {
+			t.Errorf("%s - expected the rule outside of @supports to match", tt.condition)
+		}
+	}
+}
+
+func TestParseStylesheet_nestedConditions(t *testing.T) {
+	input := `
+@supports (display: block) {
+	@media print {
+		a { color: red; }
+	}
+	b { color: red; }
+}`
+	p := NewParser(strings.NewReader(input))
+	stylesheet := p.ParseStylesheet()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+	if len(stylesheet.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(stylesheet.Rules))
+	}
+	a, b := stylesheet.Rules[0], stylesheet.Rules[1]
+	if len(a.Supports) != 1 || len(a.Media) != 1 {
+		t.Errorf("expected a in @supports and @media, got %+v", a.Conditions)
+	}
+	if len(b.Supports) != 1 || len(b.Media) != 0 {
+		t.Errorf("expected b in @supports only, got %+v", b.Conditions)
+	}
+}
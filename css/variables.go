@@ -0,0 +1,170 @@
+package css
+
+import "strings"
+
+// RawToken is a token of a raw value, with the whitespace found before it.
+type RawToken struct {
+	CSSToken
+	Space bool
+}
+
+// RawValue holds the tokens of a value which cannot be parsed with its declaration:
+// the value of a custom property, like `--gap: 4px 8px`, or a value using var(),
+// parsed once its variables are substituted.
+// See https://www.w3.org/TR/css-variables-1/
+type RawValue struct {
+	Tokens []RawToken
+
+	// Shorthand is the property declared with the value, when it is set to one of its longhands,
+	// like `margin: var(--gap)` for margin-top
+	Shorthand string
+}
+
+// IsCustomProperty reports whether a property is a custom property, like --brand-color.
+func IsCustomProperty(name string) bool {
+	return strings.HasPrefix(name, "--")
+}
+
+// parseRawTokens returns the tokens of a declaration value, up to the semicolon, the !important
+// or the brace ending it. Whitespace before the first token is dropped.
+func (p *Parser) parseRawTokens() []RawToken {
+	var tokens []RawToken
+	depth := 0
+	for p.curToken.Type != EOF {
+		switch p.curToken.Type {
+		case SEMICOLON, BANG:
+			if depth == 0 {
+				return tokens
+			}
+		case RBRACE:
+			if depth == 0 {
+				return tokens
+			}
+			depth--
+		case LPARENTHESIS, LBRACKET, LBRACE:
+			depth++
+		case RPARENTHESIS, RBRACKET:
+			if depth > 0 {
+				depth--
+			}
+		}
+		tokens = append(tokens, RawToken{CSSToken: p.curToken, Space: p.curSpace && len(tokens) > 0})
+		p.nextToken()
+	}
+	return tokens
+}
+
+// tokenReader reads the tokens of a raw value, like a lexer reads text.
+type tokenReader struct {
+	tokens      []RawToken
+	spaceBefore bool
+}
+
+func (r *tokenReader) NextToken() CSSToken {
+	if len(r.tokens) == 0 {
+		r.spaceBefore = false
+		return CSSToken{Type: EOF}
+	}
+	t := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	r.spaceBefore = t.Space
+	return t.CSSToken
+}
+
+func (r *tokenReader) hasSpaceBefore() bool {
+	return r.spaceBefore
+}
+
+// ParseValue parses the tokens of a raw value, once its variables are substituted.
+// It returns false if they are not a valid value.
+func ParseValue(tokens []RawToken) (Value, bool) {
+	value, errors := parseValueTokens(tokens)
+	return value, len(errors) == 0
+}
+
+func parseValueTokens(tokens []RawToken) (Value, []string) {
+	p := newParser(&tokenReader{tokens: tokens})
+	value := p.parseValue()
+	if p.curToken.Type != EOF {
+		p.tokenError(SEMICOLON)
+	}
+	return value, p.errors
+}
+
+// isVar tells if the tokens start with the var( function.
+func isVar(tokens []RawToken) bool {
+	return len(tokens) > 1 && tokens[0].Type == IDENTIFIER && strings.EqualFold(tokens[0].Litteral, "var") &&
+		tokens[1].Type == LPARENTHESIS && !tokens[1].Space
+}
+
+func hasVar(tokens []RawToken) bool {
+	for i := range tokens {
+		if isVar(tokens[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// closingParenthesis returns the index of the parenthesis closing the one at index open, or -1.
+func closingParenthesis(tokens []RawToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case LPARENTHESIS:
+			depth++
+		case RPARENTHESIS:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Substitute replaces the var() functions of a raw value by the value lookup returns
+// for their custom property, or by their fallback, like `var(--gap, 4px)`.
+// It returns false if a custom property has no value nor fallback.
+// See https://www.w3.org/TR/css-variables-1/#substitute-a-var
+func Substitute(tokens []RawToken, lookup func(name string) ([]RawToken, bool)) ([]RawToken, bool) {
+	var substituted []RawToken
+	for i := 0; i < len(tokens); i++ {
+		if !isVar(tokens[i:]) {
+			substituted = append(substituted, tokens[i])
+			continue
+		}
+
+		end := closingParenthesis(tokens, i+1)
+		if end < 0 {
+			return nil, false
+		}
+		args := tokens[i+2 : end]
+		if len(args) == 0 || args[0].Type != IDENTIFIER || !IsCustomProperty(args[0].Litteral) {
+			return nil, false
+		}
+		if len(args) > 1 && args[1].Type != COMMA {
+			return nil, false
+		}
+
+		value, ok := lookup(args[0].Litteral)
+		if !ok {
+			if len(args) == 1 {
+				return nil, false
+			}
+			// the fallback is everything after the first comma, it can use var() too
+			if value, ok = Substitute(args[2:], lookup); !ok {
+				return nil, false
+			}
+		}
+
+		// the value takes the place of the var(), with the whitespace before it
+		value = append([]RawToken(nil), value...)
+		if len(value) > 0 {
+			value[0].Space = tokens[i].Space
+		}
+		substituted = append(substituted, value...)
+		i = end
+	}
+	return substituted, true
+}
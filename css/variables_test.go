@@ -0,0 +1,118 @@
+package css
+
+import (
+	"strings"
+	"testing"
+)
+
+// rawText returns the tokens of a raw value as text, separated by the whitespace found between them.
+func rawText(tokens []RawToken) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		if t.Space {
+			b.WriteString(" ")
+		}
+		b.WriteString(t.Litteral)
+	}
+	return b.String()
+}
+
+func TestParseDeclarations_customProperties(t *testing.T) {
+	p := NewParser(strings.NewReader(
+		"--brand-color: #0af; --empty:; --Block: { a: b } [1] ; color: var(--brand-color, black) !important; margin: 4px",
+	))
+
+	declarations := p.ParseDeclarations()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	tests := []struct {
+		name      string
+		raw       string
+		important bool
+	}{
+		{"--brand-color", "#0af", false},
+		{"--empty", "", false},
+		{"--Block", "{ a: b } [1]", false},
+		{"color", "var(--brand-color, black)", true},
+	}
+	if len(declarations) != len(tests)+1 {
+		t.Fatalf("expected %d declarations, got %d", len(tests)+1, len(declarations))
+	}
+	for i, tt := range tests {
+		d := declarations[i]
+		if d.Name != tt.name || d.Value.Raw == nil || d.Important != tt.important {
+			t.Errorf("declarations[%d] - expected a raw %s, got %v", i, tt.name, d)
+			continue
+		}
+		if actual := rawText(d.Value.Raw.Tokens); actual != tt.raw {
+			t.Errorf("%s - expected raw value %q, got %q", tt.name, tt.raw, actual)
+		}
+	}
+	if margin := declarations[4]; margin.Value != (Value{Length: Length{Quantity: 4, Unit: Px}}) {
+		t.Errorf("expected a parsed margin, got %v", margin)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	raw := func(text string) []RawToken {
+		d := NewParser(strings.NewReader("--x: " + text)).ParseDeclarations()
+		return d[0].Value.Raw.Tokens
+	}
+	variables := map[string][]RawToken{
+		"--gap":   raw("4px 8px"),
+		"--color": raw("#0af"),
+		"--empty": raw(""),
+	}
+	lookup := func(name string) ([]RawToken, bool) {
+		tokens, ok := variables[name]
+		return tokens, ok
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"var(--gap)", "4px 8px", true},
+		{"1px var(--gap) 2px", "1px 4px 8px 2px", true},
+		{"calc(var(--gap))", "calc(4px 8px)", true},
+		{"VAR(--color, red)", "#0af", true},
+		{"var(--missing, 1px solid)", "1px solid", true},
+		{"var(--missing, var(--color))", "#0af", true},
+		{"var(--missing,)", "", true},
+		{"a var(--empty) b", "a b", true},
+		{"var(--missing)", "", false},
+		{"var(--missing, var(--none))", "", false},
+		{"var(gap)", "", false},
+		{"var(--gap red)", "", false},
+		{"var(--gap", "", false},
+	}
+	for _, tt := range tests {
+		actual, ok := Substitute(raw(tt.input), lookup)
+		if ok != tt.ok {
+			t.Errorf("%s - expected ok: %v", tt.input, tt.ok)
+			continue
+		}
+		if text := rawText(actual); ok && text != tt.expected {
+			t.Errorf("%s - expected: %q actual: %q", tt.input, tt.expected, text)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	d := NewParser(strings.NewReader("--x: 1px solid red")).ParseDeclarations()
+	value, ok := ParseValue(d[0].Value.Raw.Tokens)
+	if !ok || value.List == nil || len(value.List.Values) != 3 {
+		t.Fatalf("expected a list of 3 values, got %v", value)
+	}
+	if value.List.Values[0] != (Value{Length: Length{Quantity: 1, Unit: Px}}) {
+		t.Errorf("expected 1px, got %v", value.List.Values[0])
+	}
+
+	d = NewParser(strings.NewReader("--x: 1px )")).ParseDeclarations()
+	if _, ok := ParseValue(d[0].Value.Raw.Tokens); ok {
+		t.Error("expected an invalid value")
+	}
+}
@@ -0,0 +1,282 @@
+// Package font loads the fonts used to measure and draw text.
+//
+// A Collection holds the font files available to the renderer.
+// It resolves CSS font properties to a Face, which gives the metrics
+// needed by the layout and the font.Face needed to draw glyphs.
+package font
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+	imgfont "golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonobolditalic"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+const (
+	// fallbackFamily is the bundled family used when no other family matches
+	fallbackFamily = "go"
+
+	// monospaceFamily is the bundled family used for the monospace generic family
+	monospaceFamily = "go mono"
+)
+
+// Default is the collection used to lay out and paint text.
+var Default = NewCollection()
+
+// Collection is a set of fonts, indexed by family name.
+type Collection struct {
+	mu    sync.Mutex
+	fonts map[string][]*entry
+	faces map[faceKey]*Face
+}
+
+// entry is a single font file of a family.
+type entry struct {
+	font   *truetype.Font
+	bold   bool
+	italic bool
+}
+
+type faceKey struct {
+	entry *entry
+	size  float64
+}
+
+// NewCollection returns a collection holding the bundled Go fonts.
+func NewCollection() *Collection {
+	c := &Collection{
+		fonts: make(map[string][]*entry),
+		faces: make(map[faceKey]*Face),
+	}
+
+	bundled := []struct {
+		family       string
+		bold, italic bool
+		ttf          []byte
+	}{
+		{fallbackFamily, false, false, goregular.TTF},
+		{fallbackFamily, true, false, gobold.TTF},
+		{fallbackFamily, false, true, goitalic.TTF},
+		{fallbackFamily, true, true, gobolditalic.TTF},
+		{monospaceFamily, false, false, gomono.TTF},
+		{monospaceFamily, true, false, gomonobold.TTF},
+		{monospaceFamily, false, true, gomonoitalic.TTF},
+		{monospaceFamily, true, true, gomonobolditalic.TTF},
+	}
+	for _, b := range bundled {
+		f, err := truetype.Parse(b.ttf)
+		if err != nil {
+			panic("cannot parse bundled font: " + err.Error())
+		}
+		c.Register(b.family, b.bold, b.italic, f)
+	}
+
+	return c
+}
+
+// Register adds a font to the collection under the given family name.
+func (c *Collection) Register(family string, bold, italic bool, f *truetype.Font) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	family = strings.ToLower(family)
+	c.fonts[family] = append(c.fonts[family], &entry{font: f, bold: bold, italic: italic})
+}
+
+// LoadFile adds a font file with TrueType outlines to the collection, a .ttf or .otf file.
+// OpenType files with CFF outlines are not supported.
+// Its family and style are read from the file.
+func (c *Collection) LoadFile(path string) error {
+	f, err := parseFile(path)
+	if err != nil {
+		return err
+	}
+
+	family := f.Name(truetype.NameIDFontFamily)
+	if family == "" {
+		family = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	subfamily := strings.ToLower(f.Name(truetype.NameIDFontSubfamily))
+	bold := strings.Contains(subfamily, "bold")
+	italic := strings.Contains(subfamily, "italic") || strings.Contains(subfamily, "oblique")
+
+	c.Register(family, bold, italic, f)
+	return nil
+}
+
+// LoadFace adds a font file with TrueType outlines to the collection under the given family,
+// with the style of the CSS font-weight and font-style values, like the fonts of @font-face rules.
+func (c *Collection) LoadFace(path, family, weight, style string) error {
+	f, err := parseFile(path)
+	if err != nil {
+		return err
+	}
+	c.Register(family, isBold(weight), isItalic(style), f)
+	return nil
+}
+
+// Alias makes the fonts of a family of the collection available under another family name,
+// like the local() fonts of @font-face rules.
+// The fonts matching the CSS font-weight and font-style values are used.
+func (c *Collection) Alias(family, local, weight, style string) bool {
+	c.mu.Lock()
+	entries, ok := c.fonts[strings.ToLower(local)]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	e := bestMatch(entries, isBold(weight), isItalic(style))
+	c.Register(family, isBold(weight), isItalic(style), e.font)
+	return true
+}
+
+func parseFile(path string) (*truetype.Font, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(b, []byte("OTTO")) {
+		return nil, fmt.Errorf("cannot parse font %s: CFF outlines are not supported, only TrueType ones", path)
+	}
+	f, err := truetype.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse font %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// LoadDir adds every .ttf and .otf file found in dir to the collection.
+// The files which cannot be loaded, like OpenType fonts with CFF outlines, are skipped,
+// and the returned error reports them.
+func (c *Collection) LoadDir(dir string) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var skipped []string
+	for _, info := range infos {
+		ext := strings.ToLower(filepath.Ext(info.Name()))
+		if info.IsDir() || (ext != ".ttf" && ext != ".otf") {
+			continue
+		}
+		if err := c.LoadFile(filepath.Join(dir, info.Name())); err != nil {
+			skipped = append(skipped, err.Error())
+		}
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("skipped %d font files: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+// Face returns the face matching the CSS font-family, font-weight and font-style values,
+// at the given size in pixels.
+// family is a comma separated list of family names, the first one available is used.
+// If none is available, the bundled fallback font is used.
+func (c *Collection) Face(family, weight, style string, size float64) *Face {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bold := isBold(weight)
+	italic := isItalic(style)
+
+	var entries []*entry
+	for _, name := range strings.Split(family, ",") {
+		name = strings.ToLower(strings.Trim(strings.TrimSpace(name), `"'`))
+		switch name {
+		case "monospace":
+			name = monospaceFamily
+		case "serif", "sans-serif", "cursive", "fantasy", "system-ui":
+			name = fallbackFamily
+		}
+		if e, ok := c.fonts[name]; ok {
+			entries = e
+			break
+		}
+	}
+	if entries == nil {
+		entries = c.fonts[fallbackFamily]
+	}
+
+	e := bestMatch(entries, bold, italic)
+	key := faceKey{entry: e, size: size}
+	if face, ok := c.faces[key]; ok {
+		return face
+	}
+
+	// At 72 DPI, a point is a pixel
+	face := &Face{face: truetype.NewFace(e.font, &truetype.Options{Size: size, DPI: 72})}
+	c.faces[key] = face
+	return face
+}
+
+// bestMatch returns the entry matching the requested style,
+// an exact match first, then the same style, then the first entry.
+func bestMatch(entries []*entry, bold, italic bool) *entry {
+	for _, e := range entries {
+		if e.bold == bold && e.italic == italic {
+			return e
+		}
+	}
+	for _, e := range entries {
+		if e.italic == italic {
+			return e
+		}
+	}
+	return entries[0]
+}
+
+// isBold reports whether a CSS font-weight value should use a bold face.
+func isBold(weight string) bool {
+	switch weight {
+	case "bold", "bolder":
+		return true
+	}
+	n, err := strconv.Atoi(weight)
+	return err == nil && n >= 600
+}
+
+// isItalic reports whether a CSS font-style value should use an italic face.
+func isItalic(style string) bool {
+	return style == "italic" || style == "oblique"
+}
+
+// Face is a font at a given size.
+type Face struct {
+	face imgfont.Face
+}
+
+// Width returns the advance width of s in pixels.
+func (f *Face) Width(s string) float64 {
+	return float64(imgfont.MeasureString(f.face, s)) / 64
+}
+
+// Ascent returns the distance in pixels from the baseline to the top of the glyphs.
+func (f *Face) Ascent() float64 {
+	return float64(f.face.Metrics().Ascent) / 64
+}
+
+// Descent returns the distance in pixels from the baseline to the bottom of the glyphs.
+func (f *Face) Descent() float64 {
+	return float64(f.face.Metrics().Descent) / 64
+}
+
+// Face returns the underlying face, used to draw glyphs.
+func (f *Face) Face() imgfont.Face {
+	return f.face
+}
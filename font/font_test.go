@@ -0,0 +1,108 @@
+package font
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+func TestFace(t *testing.T) {
+	c := NewCollection()
+
+	regular := c.Face("", "", "", 16)
+	if regular != c.Face("Unknown, sans-serif", "normal", "normal", 16) {
+		t.Error("unknown families should fall back to the bundled font")
+	}
+	if regular == c.Face("", "bold", "", 16) {
+		t.Error("bold weight should use a bold face")
+	}
+	if regular == c.Face("", "700", "", 16) {
+		t.Error("numeric weight 700 should use a bold face")
+	}
+
+	mono := c.Face("monospace", "", "", 16)
+	if mono.Width("iii") != mono.Width("mmm") {
+		t.Error("monospace glyphs should all have the same width")
+	}
+	if regular.Width("iii") >= regular.Width("mmm") {
+		t.Error("proportional glyphs should have different widths")
+	}
+	if regular.Ascent() <= 0 || regular.Descent() <= 0 {
+		t.Errorf("bad metrics: ascent=%v descent=%v", regular.Ascent(), regular.Descent())
+	}
+
+	large := c.Face("", "", "", 32)
+	if large.Width("abc") <= regular.Width("abc") {
+		t.Error("larger faces should have wider glyphs")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	c := NewCollection()
+	if err := c.LoadDir("does-not-exist"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+
+	dir, err := ioutil.TempDir("", "fonts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string][]byte{
+		"cff.otf":  []byte("OTTO\x00\x0a\x00\x80"),
+		"mono.ttf": gomono.TTF,
+	}
+	for name, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the fonts with CFF outlines are skipped and reported, the others are loaded
+	err = c.LoadDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "cff.otf") {
+		t.Errorf("expected an error reporting cff.otf, got %v", err)
+	}
+	if len(c.fonts["go mono"]) != 5 {
+		t.Error("expected mono.ttf to be loaded next to the 4 bundled Go Mono fonts")
+	}
+}
+
+func TestLoadFace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fonts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mono.ttf")
+	if err := ioutil.WriteFile(path, gomono.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCollection()
+	if err := c.LoadFace(path, "Brand", "bold", "normal"); err != nil {
+		t.Fatal(err)
+	}
+	brand := c.Face("Brand", "bold", "", 16)
+	if brand.Width("iii") != brand.Width("mmm") {
+		t.Error("the Brand family should use the loaded monospace font")
+	}
+	if err := c.LoadFace(filepath.Join(dir, "missing.ttf"), "Missing", "", ""); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	if !c.Alias("Code", "Go Mono", "normal", "italic") {
+		t.Fatal("expected the bundled Go Mono family to be aliased")
+	}
+	code := c.Face("Code", "", "italic", 16)
+	if code.Width("iii") != code.Width("mmm") {
+		t.Error("the Code family should use the Go Mono font")
+	}
+	if c.Alias("Other", "Unknown", "", "") {
+		t.Error("unknown families cannot be aliased")
+	}
+}
@@ -0,0 +1,148 @@
+package lexer
+
+//go:generate go run gen_entity.go
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeCharacterReferences replaces the named and numeric character references
+// of s, like `&amp;`, `&#169;` or `&#x1F600;`, with the characters they stand for.
+// In attribute values, legacy references without semicolon are kept as is
+// when followed by an alphanumeric character or `=`, so that `?a=1&copy=2` is preserved.
+// See https://html.spec.whatwg.org/#character-reference-state
+func decodeCharacterReferences(s string, attribute bool) string {
+	i := strings.IndexByte(s, '&')
+	if i < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i >= 0 {
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		var decoded string
+		var n int
+		if len(s) > 1 && s[1] == '#' {
+			decoded, n = decodeNumericReference(s)
+		} else {
+			decoded, n = decodeNamedReference(s, attribute)
+		}
+		if n == 0 {
+			// not a character reference, keep the ampersand
+			decoded, n = "&", 1
+		}
+		b.WriteString(decoded)
+		s = s[n:]
+
+		i = strings.IndexByte(s, '&')
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// decodeNamedReference decodes the named reference at the start of s, like `&amp;`.
+// It returns the decoded characters and the number of bytes read, 0 if s does not start
+// with a known reference.
+func decodeNamedReference(s string, attribute bool) (string, int) {
+	end := 1
+	for end < len(s) && isAlphanumeric(s[end]) {
+		end++
+	}
+	name := s[1:end]
+	if name == "" {
+		return "", 0
+	}
+
+	if end < len(s) && s[end] == ';' {
+		if v, ok := entities[name+";"]; ok {
+			return v, end + 1
+		}
+	}
+
+	// legacy references may omit the semicolon, the longest one matching is used
+	max := len(name)
+	if max > longestLegacyEntity {
+		max = longestLegacyEntity
+	}
+	for l := max; l > 1; l-- {
+		v, ok := entities[name[:l]]
+		if !ok {
+			continue
+		}
+		if attribute && 1+l < len(s) && (isAlphanumeric(s[1+l]) || s[1+l] == '=') {
+			return "", 0
+		}
+		return v, 1 + l
+	}
+	return "", 0
+}
+
+// decodeNumericReference decodes the numeric reference at the start of s, like `&#169;` or `&#xA9;`.
+// It returns the decoded character and the number of bytes read, 0 if s has no digits.
+func decodeNumericReference(s string) (string, int) {
+	i := 2
+	base := rune(10)
+	if i < len(s) && (s[i] == 'x' || s[i] == 'X') {
+		base = 16
+		i++
+	}
+
+	start := i
+	var code rune
+	for ; i < len(s); i++ {
+		d := digitValue(s[i], base)
+		if d < 0 {
+			break
+		}
+		// saturate to avoid overflowing, the value is out of range anyway
+		if code <= utf8.MaxRune {
+			code = code*base + d
+		}
+	}
+	if i == start {
+		return "", 0
+	}
+	if i < len(s) && s[i] == ';' {
+		i++
+	}
+
+	switch {
+	case code == 0, code > utf8.MaxRune, 0xD800 <= code && code <= 0xDFFF:
+		code = utf8.RuneError
+	case 0x80 <= code && code <= 0x9F:
+		if r := windows1252[code-0x80]; r != 0 {
+			code = r
+		}
+	}
+	return string(code), i
+}
+
+func digitValue(ch byte, base rune) rune {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return rune(ch - '0')
+	case base == 16 && 'a' <= ch && ch <= 'f':
+		return rune(ch-'a') + 10
+	case base == 16 && 'A' <= ch && ch <= 'F':
+		return rune(ch-'A') + 10
+	}
+	return -1
+}
+
+func isAlphanumeric(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+}
+
+// windows1252 maps the C1 control codes 0x80 to 0x9F, which documents use
+// for the characters of the windows-1252 encoding. 0 means no replacement.
+// See https://html.spec.whatwg.org/#numeric-character-reference-end-state
+var windows1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
@@ -0,0 +1,102 @@
+//go:build ignore
+// +build ignore
+
+// gen_entity generates entity.go, the table of the named character references,
+// from the entities.json file of the HTML standard.
+//
+//	go run gen_entity.go [-in entities.json]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+const entitiesURL = "https://html.spec.whatwg.org/entities.json"
+
+func main() {
+	in := flag.String("in", entitiesURL, "URL or path of entities.json")
+	out := flag.String("out", "entity.go", "generated file")
+	flag.Parse()
+
+	r, err := open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	var references map[string]struct {
+		Codepoints []rune `json:"codepoints"`
+	}
+	if err := json.NewDecoder(r).Decode(&references); err != nil {
+		log.Fatalf("cannot decode %s: %v", *in, err)
+	}
+
+	names := make([]string, 0, len(references))
+	longestLegacy := 0
+	for name := range references {
+		name = strings.TrimPrefix(name, "&")
+		names = append(names, name)
+		if !strings.HasSuffix(name, ";") && len(name) > longestLegacy {
+			longestLegacy = len(name)
+		}
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated from %s. DO NOT EDIT.\n\n", entitiesURL)
+	b.WriteString("package lexer\n\n")
+	b.WriteString("// longestLegacyEntity is the length of the longest named character reference\n")
+	b.WriteString("// which may be written without its semicolon.\n")
+	fmt.Fprintf(&b, "const longestLegacyEntity = %d\n\n", longestLegacy)
+	b.WriteString("// entities maps the HTML5 named character references to their characters.\n")
+	b.WriteString("// Legacy references, which may omit the semicolon, are listed both with and without it.\n")
+	b.WriteString("// See https://html.spec.whatwg.org/multipage/named-characters.html\n")
+	b.WriteString("var entities = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "%q: \"", name)
+		for _, c := range references["&"+name].Codepoints {
+			if c > 0xFFFF {
+				fmt.Fprintf(&b, "\\U%08X", c)
+			} else {
+				fmt.Fprintf(&b, "\\u%04X", c)
+			}
+		}
+		b.WriteString("\",\n")
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// open returns the content of entities.json, from a URL or a local file.
+func open(in string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(in, "http://") && !strings.HasPrefix(in, "https://") {
+		return os.Open(in)
+	}
+	resp, err := http.Get(in)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cannot download %s: %s", in, resp.Status)
+	}
+	return resp.Body, nil
+}
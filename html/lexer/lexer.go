@@ -20,6 +20,19 @@ type Lexer struct {
 
 	ch  byte // current char
 	lex lexFn
+
+	prev Token  // last token returned
+	tag  string // name of the opening element being tokenized
+}
+
+// rawTextElements are the elements whose content is read as text up to their closing element.
+// The character references of RCDATA elements are decoded, the raw text ones are kept as is.
+// See https://html.spec.whatwg.org/#elements-2
+var rawTextElements = map[string]struct{ rcdata bool }{
+	"script":   {rcdata: false},
+	"style":    {rcdata: false},
+	"textarea": {rcdata: true},
+	"title":    {rcdata: true},
 }
 
 // New instanciates a new Lexer.
@@ -51,6 +64,20 @@ func (l *Lexer) readChar() {
 func (l *Lexer) NextToken() Token {
 	tok, fn := l.lex(l)
 	l.lex = fn
+	tok.End = l.position
+
+	switch {
+	case tok.Type == TokenLBracket:
+		l.tag = ""
+	case tok.Type == TokenIdent && l.prev.Type == TokenLBracket:
+		l.tag = strings.ToLower(tok.Literal)
+	case tok.Type == TokenRBracket && l.prev.Type != TokenSlash:
+		// the content of the element is text, a self-closing element has none
+		if _, ok := rawTextElements[l.tag]; ok {
+			l.lex = lexRawText
+		}
+	}
+	l.prev = tok
 	return tok
 }
 
@@ -72,15 +99,25 @@ func lexNode(l *Lexer) (tok Token, fn lexFn) {
 		tok.Literal = string(l.ch)
 	case '\'', '"':
 		tok.Type = TokenString
-		tok.Literal = l.readString()
+		tok.Literal = decodeCharacterReferences(l.readString(), true)
 	case '<':
+		switch {
+		case l.hasPrefix("<!--"):
+			tok.Type = TokenComment
+			tok.Literal = l.readDelimited("<!--", "-->")
+			return tok, lexText
+		case l.hasPrefix("<![CDATA["):
+			tok.Type = TokenCDATA
+			tok.Literal = l.readDelimited("<![CDATA[", "]]>")
+			return tok, lexText
+		}
 		tok.Type = TokenLBracket
 		tok.Literal = string(l.ch)
 	case '>':
 		tok.Type = TokenRBracket
 		tok.Literal = string(l.ch)
 		fn = lexText
-	case '0':
+	case 0:
 		tok.Literal, tok.Type = "", TokenEOF
 	default:
 		if isLetter(l.ch) {
@@ -112,11 +149,10 @@ func lexText(l *Lexer) (tok Token, fn lexFn) {
 			}
 
 			tok.Type = TokenText
-			tok.Literal = trimmedText
+			tok.Literal = decodeCharacterReferences(trimmedText, false)
 			fn = lexNode
 			break
 		}
-		//TODO: detect HTML character
 		l.readChar()
 	}
 	// if the text node is empty we call the next lexer
@@ -126,6 +162,41 @@ func lexText(l *Lexer) (tok Token, fn lexFn) {
 	return
 }
 
+// lexRawText tokenizes the content of a raw text element like <script> or <style>,
+// up to the closing element matching the opening one.
+func lexRawText(l *Lexer) (tok Token, fn lexFn) {
+	tok = newToken(l)
+	element := rawTextElements[l.tag]
+
+	for l.ch != 0 && !l.hasClosingElement(l.tag) {
+		l.readChar()
+	}
+	if l.position == tok.Position {
+		return lexNode(l)
+	}
+
+	tok.Type = TokenText
+	tok.Literal = l.input[tok.Position:l.position]
+	if element.rcdata {
+		tok.Literal = decodeCharacterReferences(tok.Literal, false)
+	}
+	return tok, lexNode
+}
+
+// hasClosingElement reports whether the input continues with the closing element of tag,
+// like `</script>` or `</SCRIPT >`.
+func (l *Lexer) hasClosingElement(tag string) bool {
+	end := l.position + 2 + len(tag)
+	if !l.hasPrefix("</") || end > len(l.input) || !strings.EqualFold(l.input[l.position+2:end], tag) {
+		return false
+	}
+	if end == len(l.input) {
+		return true
+	}
+	ch := l.input[end]
+	return isWhitespace(ch) || ch == '/' || ch == '>'
+}
+
 func newToken(l *Lexer) Token {
 	return Token{
 		Position:     l.position,
@@ -136,7 +207,7 @@ func newToken(l *Lexer) Token {
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -146,6 +217,10 @@ func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '-'
 }
 
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
 func isWhitespace(ch byte) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
@@ -156,11 +231,33 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+func (l *Lexer) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(l.input[l.position:], prefix)
+}
+
+// readDelimited reads everything between open and close, skipping both delimiters.
+// An unterminated section runs until the end of the input.
+func (l *Lexer) readDelimited(open, close string) string {
+	start := l.position + len(open)
+	end := strings.Index(l.input[start:], close)
+	if end < 0 {
+		end = len(l.input)
+	} else {
+		end += start
+	}
+
+	for l.position < end+len(close) && l.ch != 0 {
+		l.readChar()
+	}
+	return l.input[start:end]
+}
+
 func (l *Lexer) readString() string {
+	quote := l.ch
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == '\'' || l.ch == 0 {
+		if l.ch == quote || l.ch == 0 {
 			break
 		}
 	}
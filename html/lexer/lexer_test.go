@@ -79,3 +79,144 @@ func TestNextToken_position(t *testing.T) {
 		}
 	}
 }
+
+func TestNextToken_comment(t *testing.T) {
+	input := `<a><!-- a <b>comment</b> --><![CDATA[x < y]]></a><!-- unterminated`
+
+	tests := []struct {
+		Type    TokenType
+		Literal string
+	}{
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenIdent, Literal: "a"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenComment, Literal: " a <b>comment</b> "},
+		{Type: TokenCDATA, Literal: "x < y"},
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenSlash, Literal: "/"},
+		{Type: TokenIdent, Literal: "a"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenComment, Literal: " unterminated"},
+		{Type: TokenEOF, Literal: ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.Type {
+			t.Fatalf("tests[%d]: bad token type. expected=%q got=%q", i, tt.Type, tok.Type)
+		}
+		if tok.Literal != tt.Literal {
+			t.Fatalf("tests[%d]: bad literal. expected=%q got=%q", i, tt.Literal, tok.Literal)
+		}
+	}
+}
+
+func TestNextToken_characterReferences(t *testing.T) {
+	input := `<a href="?x=1&amp;y=2&copy=3&lt">&lt;b&gt; &copy; &#169;&#xA9; &#x1F600; &notit; &#0; &#150; &bogus;</a>`
+
+	tests := []struct {
+		Type     TokenType
+		Literal  string
+		Position int
+		End      int
+	}{
+		{Type: TokenLBracket, Literal: "<", Position: 0, End: 1},
+		{Type: TokenIdent, Literal: "a", Position: 1, End: 2},
+		{Type: TokenIdent, Literal: "href", Position: 3, End: 7},
+		{Type: TokenEqual, Literal: "=", Position: 7, End: 8},
+		{Type: TokenString, Literal: "?x=1&y=2&copy=3<", Position: 8, End: 32},
+		{Type: TokenRBracket, Literal: ">", Position: 32, End: 33},
+		{Type: TokenText, Literal: "<b> © ©© 😀 ¬it; � – &bogus;", Position: 33, End: 100},
+		{Type: TokenLBracket, Literal: "<", Position: 100, End: 101},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.Type {
+			t.Fatalf("tests[%d]: bad token type. expected=%q got=%q", i, tt.Type, tok.Type)
+		}
+		if tok.Literal != tt.Literal {
+			t.Fatalf("tests[%d]: bad literal. expected=%q got=%q", i, tt.Literal, tok.Literal)
+		}
+		if tok.Position != tt.Position || tok.End != tt.End {
+			t.Fatalf("tests[%d]: bad range. expected=[%d:%d] got=[%d:%d]", i, tt.Position, tt.End, tok.Position, tok.End)
+		}
+	}
+}
+
+func TestNextToken_rawText(t *testing.T) {
+	input := `<style>a < b {}</style><script>if (a<b) { x = "</scripts>" }</SCRIPT ><title>a &amp; <b></title><script/>x`
+
+	tests := []struct {
+		Type    TokenType
+		Literal string
+	}{
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenIdent, Literal: "style"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenText, Literal: "a < b {}"},
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenSlash, Literal: "/"},
+		{Type: TokenIdent, Literal: "style"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenIdent, Literal: "script"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenText, Literal: `if (a<b) { x = "</scripts>" }`},
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenSlash, Literal: "/"},
+		{Type: TokenIdent, Literal: "SCRIPT"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenIdent, Literal: "title"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenText, Literal: "a & <b>"},
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenSlash, Literal: "/"},
+		{Type: TokenIdent, Literal: "title"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenLBracket, Literal: "<"},
+		{Type: TokenIdent, Literal: "script"},
+		{Type: TokenSlash, Literal: "/"},
+		{Type: TokenRBracket, Literal: ">"},
+		{Type: TokenText, Literal: "x"},
+		{Type: TokenEOF, Literal: ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.Type {
+			t.Fatalf("tests[%d]: bad token type. expected=%q got=%q", i, tt.Type, tok.Type)
+		}
+		if tok.Literal != tt.Literal {
+			t.Fatalf("tests[%d]: bad literal. expected=%q got=%q", i, tt.Literal, tok.Literal)
+		}
+	}
+}
+
+func Test_entities(t *testing.T) {
+	// https://html.spec.whatwg.org/entities.json lists 2231 named character references
+	if len(entities) != 2231 {
+		t.Errorf("expected 2231 entities, got %d", len(entities))
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"amp;", "&"},
+		{"AMP", "&"},
+		{"nGt;", "≫⃒"},
+		{"nLt;", "≪⃒"},
+		{"nGtv;", "≫̸"},
+		{"fopf;", "\U0001D557"},
+	}
+	for _, tt := range tests {
+		if got := entities[tt.name]; got != tt.want {
+			t.Errorf("entities[%q] = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
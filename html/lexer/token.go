@@ -10,6 +10,7 @@ const (
 	TokenString  = "String"
 	TokenText    = "Text"
 	TokenComment = "Comment"
+	TokenCDATA   = "CDATA"
 
 	TokenBang     = "!"
 	TokenEqual    = "="
@@ -22,9 +23,10 @@ type TokenType string
 
 type Token struct {
 	Type    TokenType
-	Literal string
+	Literal string // character references are decoded in texts and strings
 
-	Position     int
+	Position     int // start of the token in the input
+	End          int // end of the token in the input, input[Position:End] is its source
 	Line         int
 	LinePosition int
 }
@@ -33,7 +35,7 @@ func (t Token) String() string {
 	switch t.Type {
 	case TokenError:
 		return fmt.Sprintf("error at line %d (%d): %s", t.Line, t.LinePosition, t.Literal)
-	case TokenIdent, TokenString, TokenText, TokenComment:
+	case TokenIdent, TokenString, TokenText, TokenComment, TokenCDATA:
 		if len(t.Literal) > 10 {
 			return fmt.Sprintf("%.10q...", t.Literal)
 		}
@@ -11,6 +11,22 @@ const (
 
 type NodeType string
 
+const (
+	NoQuirks      QuirksMode = "no-quirks"
+	Quirks        QuirksMode = "quirks"
+	LimitedQuirks QuirksMode = "limited-quirks"
+)
+
+// QuirksMode is the rendering mode of a document, selected by its doctype.
+type QuirksMode string
+
+// Doctype represents the document type declaration: <!DOCTYPE name PUBLIC "public" "system">
+type Doctype struct {
+	Name     string
+	PublicID string
+	SystemID string
+}
+
 type Node struct {
 	Parent      *Node
 	FirstChild  *Node
@@ -24,6 +40,11 @@ type Node struct {
 	Attributes map[string]string
 
 	TextContent string
+
+	// Doctype and QuirksMode are only set on the document root.
+	// Doctype is nil if the document has no doctype.
+	Doctype    *Doctype
+	QuirksMode QuirksMode
 }
 
 func (n *Node) String() string {
@@ -38,8 +59,9 @@ func (n *Node) String() string {
 	)
 }
 
+// AddChild appends c to the children of n.
 func (n *Node) AddChild(c *Node) {
-	c.Parent = c
+	c.Parent = n
 	if n.FirstChild == nil {
 		n.FirstChild = c
 		n.LastChild = c
@@ -49,3 +71,43 @@ func (n *Node) AddChild(c *Node) {
 	c.PrevSibling = n.LastChild
 	n.LastChild = c
 }
+
+// InsertBefore inserts c in the children of n, just before ref.
+// If ref is nil, c is appended.
+func (n *Node) InsertBefore(c, ref *Node) {
+	if ref == nil {
+		n.AddChild(c)
+		return
+	}
+	c.Parent = n
+	c.NextSibling = ref
+	c.PrevSibling = ref.PrevSibling
+	if ref.PrevSibling != nil {
+		ref.PrevSibling.NextSibling = c
+	} else {
+		n.FirstChild = c
+	}
+	ref.PrevSibling = c
+}
+
+// RemoveChild detaches c from the children of n.
+func (n *Node) RemoveChild(c *Node) {
+	if c.Parent != n {
+		return
+	}
+	if n.FirstChild == c {
+		n.FirstChild = c.NextSibling
+	}
+	if n.LastChild == c {
+		n.LastChild = c.PrevSibling
+	}
+	if c.PrevSibling != nil {
+		c.PrevSibling.NextSibling = c.NextSibling
+	}
+	if c.NextSibling != nil {
+		c.NextSibling.PrevSibling = c.PrevSibling
+	}
+	c.Parent = nil
+	c.PrevSibling = nil
+	c.NextSibling = nil
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/lysrt/bro/html"
+	"github.com/lysrt/bro/html/lexer"
+)
+
+// parseDoctype parses declarations like: `<!DOCTYPE html PUBLIC "public id" "system id">`
+func (p *Parser) parseDoctype() *html.Doctype {
+	if !p.expectsPeek(lexer.TokenBang) {
+		return nil
+	}
+	if !p.expectsPeek(lexer.TokenIdent) {
+		return nil
+	}
+	if !strings.EqualFold(p.curToken.Literal, "doctype") {
+		p.addError(p.curToken, "unexpected declaration %q", p.curToken.Literal)
+	}
+
+	doctype := &html.Doctype{}
+	if p.peekTokenIs(lexer.TokenIdent) {
+		p.nextToken()
+		doctype.Name = strings.ToLower(p.curToken.Literal)
+	}
+
+	if p.peekTokenIs(lexer.TokenIdent) {
+		p.nextToken()
+		switch strings.ToUpper(p.curToken.Literal) {
+		case "PUBLIC":
+			if p.peekTokenIs(lexer.TokenString) {
+				p.nextToken()
+				doctype.PublicID = p.curToken.Literal
+			}
+			if p.peekTokenIs(lexer.TokenString) {
+				p.nextToken()
+				doctype.SystemID = p.curToken.Literal
+			}
+		case "SYSTEM":
+			if p.peekTokenIs(lexer.TokenString) {
+				p.nextToken()
+				doctype.SystemID = p.curToken.Literal
+			}
+		default:
+			p.addError(p.curToken, "unexpected doctype keyword %q", p.curToken.Literal)
+		}
+	}
+
+	// skip anything left until the closing bracket
+	for !p.peekTokenIs(lexer.TokenRBracket) && !p.peekTokenIs(lexer.TokenEOF) {
+		p.nextToken()
+	}
+	if !p.expectsPeek(lexer.TokenRBracket) {
+		return nil
+	}
+	// skip RBracket
+	p.nextToken()
+
+	return doctype
+}
+
+// quirksMode returns the rendering mode selected by a doctype,
+// following https://html.spec.whatwg.org/#the-initial-insertion-mode
+func quirksMode(doctype *html.Doctype) html.QuirksMode {
+	if doctype == nil || doctype.Name != "html" {
+		return html.Quirks
+	}
+
+	public := strings.ToLower(doctype.PublicID)
+	system := strings.ToLower(doctype.SystemID)
+
+	for _, id := range quirkyPublicIDs {
+		if public == id {
+			return html.Quirks
+		}
+	}
+	if system == "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd" {
+		return html.Quirks
+	}
+	for _, prefix := range quirkyPublicPrefixes {
+		if strings.HasPrefix(public, prefix) {
+			return html.Quirks
+		}
+	}
+
+	for _, prefix := range html401Prefixes {
+		if strings.HasPrefix(public, prefix) {
+			if doctype.SystemID == "" {
+				return html.Quirks
+			}
+			return html.LimitedQuirks
+		}
+	}
+	for _, prefix := range limitedQuirkyPublicPrefixes {
+		if strings.HasPrefix(public, prefix) {
+			return html.LimitedQuirks
+		}
+	}
+
+	return html.NoQuirks
+}
+
+var quirkyPublicIDs = []string{
+	"-//w3o//dtd w3 html strict 3.0//en//",
+	"-/w3c/dtd html 4.0 transitional/en",
+	"html",
+}
+
+var quirkyPublicPrefixes = []string{
+	"+//silmaril//dtd html pro v0r11 19970101//",
+	"-//as//dtd html 3.0 aswedit + extensions//",
+	"-//advasoft ltd//dtd html 3.0 aswedit + extensions//",
+	"-//ietf//dtd html 2.0 level 1//",
+	"-//ietf//dtd html 2.0 level 2//",
+	"-//ietf//dtd html 2.0 strict level 1//",
+	"-//ietf//dtd html 2.0 strict level 2//",
+	"-//ietf//dtd html 2.0 strict//",
+	"-//ietf//dtd html 2.0//",
+	"-//ietf//dtd html 2.1e//",
+	"-//ietf//dtd html 3.0//",
+	"-//ietf//dtd html 3.2 final//",
+	"-//ietf//dtd html 3.2//",
+	"-//ietf//dtd html 3//",
+	"-//ietf//dtd html level 0//",
+	"-//ietf//dtd html level 1//",
+	"-//ietf//dtd html level 2//",
+	"-//ietf//dtd html level 3//",
+	"-//ietf//dtd html strict level 0//",
+	"-//ietf//dtd html strict level 1//",
+	"-//ietf//dtd html strict level 2//",
+	"-//ietf//dtd html strict level 3//",
+	"-//ietf//dtd html strict//",
+	"-//ietf//dtd html//",
+	"-//metrius//dtd metrius presentational//",
+	"-//microsoft//dtd internet explorer 2.0 html strict//",
+	"-//microsoft//dtd internet explorer 2.0 html//",
+	"-//microsoft//dtd internet explorer 2.0 tables//",
+	"-//microsoft//dtd internet explorer 3.0 html strict//",
+	"-//microsoft//dtd internet explorer 3.0 html//",
+	"-//microsoft//dtd internet explorer 3.0 tables//",
+	"-//netscape comm. corp.//dtd html//",
+	"-//netscape comm. corp.//dtd strict html//",
+	"-//o'reilly and associates//dtd html 2.0//",
+	"-//o'reilly and associates//dtd html extended 1.0//",
+	"-//o'reilly and associates//dtd html extended relaxed 1.0//",
+	"-//sq//dtd html 2.0 hotmetal + extensions//",
+	"-//softquad software//dtd hotmetal pro 6.0::19990601::extensions to html 4.0//",
+	"-//softquad//dtd hotmetal pro 4.0::19971010::extensions to html 4.0//",
+	"-//spyglass//dtd html 2.0 extended//",
+	"-//sun microsystems corp.//dtd hotjava html//",
+	"-//sun microsystems corp.//dtd hotjava strict html//",
+	"-//w3c//dtd html 3 1995-03-24//",
+	"-//w3c//dtd html 3.2 draft//",
+	"-//w3c//dtd html 3.2 final//",
+	"-//w3c//dtd html 3.2//",
+	"-//w3c//dtd html 3.2s draft//",
+	"-//w3c//dtd html 4.0 frameset//",
+	"-//w3c//dtd html 4.0 transitional//",
+	"-//w3c//dtd html experimental 19960712//",
+	"-//w3c//dtd html experimental 970421//",
+	"-//w3c//dtd w3 html//",
+	"-//w3o//dtd w3 html 3.0//",
+	"-//webtechs//dtd mozilla html 2.0//",
+	"-//webtechs//dtd mozilla html//",
+}
+
+// html401Prefixes select quirks mode without a system identifier,
+// limited-quirks mode otherwise.
+var html401Prefixes = []string{
+	"-//w3c//dtd html 4.01 frameset//",
+	"-//w3c//dtd html 4.01 transitional//",
+}
+
+var limitedQuirkyPublicPrefixes = []string{
+	"-//w3c//dtd xhtml 1.0 frameset//",
+	"-//w3c//dtd xhtml 1.0 transitional//",
+}
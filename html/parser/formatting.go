@@ -0,0 +1,204 @@
+package parser
+
+import "github.com/lysrt/bro/html"
+
+// formattingElements are the elements reopened when they are misnested: <b>1<p>2</b>3</p>
+// See https://html.spec.whatwg.org/#formatting
+var formattingElements = map[string]bool{
+	"a":      true,
+	"b":      true,
+	"big":    true,
+	"code":   true,
+	"em":     true,
+	"font":   true,
+	"i":      true,
+	"nobr":   true,
+	"s":      true,
+	"small":  true,
+	"strike": true,
+	"strong": true,
+	"tt":     true,
+	"u":      true,
+}
+
+// formattingElement returns the last active formatting element named tag,
+// after the last marker.
+func (p *Parser) formattingElement(tag string) *html.Node {
+	for i := len(p.formatting) - 1; i >= 0; i-- {
+		e := p.formatting[i]
+		if e == nil {
+			return nil
+		}
+		if e.Tag == tag {
+			return e
+		}
+	}
+	return nil
+}
+
+// removeFormatting removes n from the list of active formatting elements.
+func (p *Parser) removeFormatting(n *html.Node) {
+	if i := indexOf(p.formatting, n); i >= 0 {
+		p.formatting = append(p.formatting[:i], p.formatting[i+1:]...)
+	}
+}
+
+// clearFormattingToMarker removes the active formatting elements up to the last marker.
+func (p *Parser) clearFormattingToMarker() {
+	for len(p.formatting) > 0 {
+		e := p.formatting[len(p.formatting)-1]
+		p.formatting = p.formatting[:len(p.formatting)-1]
+		if e == nil {
+			return
+		}
+	}
+}
+
+// reconstructFormatting reopens the formatting elements closed by a misnested element.
+// See https://html.spec.whatwg.org/#reconstruct-the-active-formatting-elements
+func (p *Parser) reconstructFormatting() {
+	if len(p.formatting) == 0 {
+		return
+	}
+	isOpen := func(e *html.Node) bool {
+		return e == nil || indexOf(p.elements, e) >= 0
+	}
+
+	i := len(p.formatting) - 1
+	if isOpen(p.formatting[i]) {
+		return
+	}
+	// rewind to the first entry which is closed
+	for i > 0 && !isOpen(p.formatting[i-1]) {
+		i--
+	}
+	// reopen each entry from there
+	for ; i < len(p.formatting); i++ {
+		clone := cloneElement(p.formatting[i])
+		p.insertElement(clone, false)
+		p.formatting[i] = clone
+	}
+}
+
+// adoptionAgency closes a formatting element, reopening it in the elements it was misnested with.
+// It returns false when there is no such formatting element, in which case
+// the closing element must be handled as any other closing element.
+// See https://html.spec.whatwg.org/#adoption-agency-algorithm
+func (p *Parser) adoptionAgency(tag string) bool {
+	current := p.currentNode()
+	if current.Tag == tag && indexOf(p.formatting, current) < 0 {
+		p.pop()
+		return true
+	}
+
+	for outer := 0; outer < 8; outer++ {
+		formattingElement := p.formattingElement(tag)
+		if formattingElement == nil {
+			return false
+		}
+		feIndex := indexOf(p.elements, formattingElement)
+		if feIndex < 0 {
+			p.addError(p.curToken, "unexpected closing element %q", tag)
+			p.removeFormatting(formattingElement)
+			return true
+		}
+		if !p.inScope(defaultScope, tag) {
+			p.addError(p.curToken, "unexpected closing element %q", tag)
+			return true
+		}
+		if formattingElement != p.currentNode() {
+			p.addError(p.curToken, "misnested closing element %q", tag)
+		}
+
+		// The furthest block is the first special element opened inside the formatting element
+		var furthestBlock *html.Node
+		for _, e := range p.elements[feIndex+1:] {
+			if specialElements[e.Tag] {
+				furthestBlock = e
+				break
+			}
+		}
+		if furthestBlock == nil {
+			for p.pop() != formattingElement {
+			}
+			p.removeFormatting(formattingElement)
+			return true
+		}
+
+		commonAncestor := p.elements[feIndex-1]
+		bookmark := indexOf(p.formatting, formattingElement)
+
+		node, lastNode := furthestBlock, furthestBlock
+		nodeIndex := indexOf(p.elements, node)
+		for inner := 1; ; inner++ {
+			nodeIndex--
+			node = p.elements[nodeIndex]
+			if node == formattingElement {
+				break
+			}
+			if inner > 3 && indexOf(p.formatting, node) >= 0 {
+				p.removeFormatting(node)
+			}
+			listIndex := indexOf(p.formatting, node)
+			if listIndex < 0 {
+				p.elements = append(p.elements[:nodeIndex], p.elements[nodeIndex+1:]...)
+				continue
+			}
+
+			clone := cloneElement(node)
+			p.formatting[listIndex] = clone
+			p.elements[nodeIndex] = clone
+			node = clone
+			if lastNode == furthestBlock {
+				bookmark = listIndex + 1
+			}
+			detach(lastNode)
+			node.AddChild(lastNode)
+			lastNode = node
+		}
+
+		detach(lastNode)
+		commonAncestor.AddChild(lastNode)
+
+		// Move the content of the furthest block into a new formatting element
+		clone := cloneElement(formattingElement)
+		for c := furthestBlock.FirstChild; c != nil; c = furthestBlock.FirstChild {
+			furthestBlock.RemoveChild(c)
+			clone.AddChild(c)
+		}
+		furthestBlock.AddChild(clone)
+
+		if i := indexOf(p.formatting, formattingElement); i >= 0 && i < bookmark {
+			bookmark--
+		}
+		p.removeFormatting(formattingElement)
+		if bookmark > len(p.formatting) {
+			bookmark = len(p.formatting)
+		}
+		p.formatting = append(p.formatting[:bookmark], append([]*html.Node{clone}, p.formatting[bookmark:]...)...)
+
+		p.removeElement(formattingElement)
+		fbIndex := indexOf(p.elements, furthestBlock)
+		p.elements = append(p.elements[:fbIndex+1], append([]*html.Node{clone}, p.elements[fbIndex+1:]...)...)
+	}
+	return true
+}
+
+// cloneElement returns a new element with the same tag and attributes as n.
+func cloneElement(n *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:       n.Type,
+		Tag:        n.Tag,
+		Attributes: make(map[string]string, len(n.Attributes)),
+	}
+	for k, v := range n.Attributes {
+		clone.Attributes[k] = v
+	}
+	return clone
+}
+
+func detach(n *html.Node) {
+	if n.Parent != nil {
+		n.Parent.RemoveChild(n)
+	}
+}
@@ -2,15 +2,12 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/lysrt/bro/html"
 	"github.com/lysrt/bro/html/lexer"
 )
 
-// nodeClosingElement is a special node for HTML closing element.
-// It should not escapes outside of the parser.
-const nodeClosingElement html.NodeType = "closing element"
-
 // Error represents a parser error.
 type Error struct {
 	Token lexer.Token
@@ -28,7 +25,14 @@ type Parser struct {
 	curToken  lexer.Token
 	peekToken lexer.Token
 
-	elements []*html.Node
+	// tree construction state
+	mode       insertionMode
+	root       *html.Node
+	head       *html.Node
+	body       *html.Node
+	elements   []*html.Node // stack of open elements
+	formatting []*html.Node // list of active formatting elements, nil is a marker
+	doctype    *html.Doctype
 
 	errors []Error
 }
@@ -56,74 +60,75 @@ func (p *Parser) nextToken() {
 }
 
 // Parse parses the document and return a html.tree.
-// Before using node check for parser error with Errors.
+// Misnested or unclosed elements are fixed up the way browsers do,
+// and reported in Errors.
 func (p *Parser) Parse() *html.Node {
-	htmlNode := &html.Node{Type: html.NodeElement, Tag: "html"}
-	head := &html.Node{Type: html.NodeElement, Tag: "head"}
-	body := &html.Node{Type: html.NodeElement, Tag: "body"}
+	p.mode = initial
+	p.root = &html.Node{Type: html.NodeElement, Tag: "html", Attributes: map[string]string{}}
+	p.head = nil
+	p.body = nil
+	p.elements = []*html.Node{p.root}
+	p.formatting = nil
 
-	n := p.parseNode()
-	if n == nil {
-		return nil
-	}
-	if n.Type == html.NodeElement && n.Tag == "html" {
-		htmlNode = n
-	}
-	if n.Type == html.NodeElement && n.Tag == "head" {
-		head = n
-		n = p.parseNode()
+	for !p.curTokenIs(lexer.TokenEOF) {
+		p.parseToken()
 	}
-	htmlNode.AddChild(head)
-	if n.Type == html.NodeElement && n.Tag == "body" {
-		body = n
-		n = p.parseNode()
-	}
-	htmlNode.AddChild(body)
-	for n != nil {
-		body.AddChild(n)
-		n = p.parseNode()
-	}
-	return htmlNode
+	p.endOfFile()
+
+	p.root.Doctype = p.doctype
+	p.root.QuirksMode = quirksMode(p.doctype)
+	return p.root
 }
 
-func (p *Parser) parseNode() (n *html.Node) {
+// parseToken reads the next node or closing element and adds it to the tree.
+func (p *Parser) parseToken() {
 	switch p.curToken.Type {
 	case lexer.TokenLBracket:
 		if p.peekTokenIs(lexer.TokenBang) {
-			//TODO: handle doctype
-			p.addError(p.peekToken, "doctype not implemented")
+			tok := p.curToken
+			if doctype := p.parseDoctype(); doctype != nil {
+				if p.mode != initial || p.doctype != nil {
+					p.addError(tok, "unexpected doctype")
+				} else {
+					p.doctype = doctype
+				}
+			}
 		} else if p.peekTokenIs(lexer.TokenSlash) {
-			n = p.parseClosingElement()
+			tok := p.curToken
+			if tag, ok := p.parseClosingElement(); ok {
+				p.endTag(tok, tag)
+			}
 		} else if p.peekTokenIs(lexer.TokenIdent) {
-			n = p.parseElement()
+			tok := p.curToken
+			if elem, selfClosing := p.parseElement(); elem != nil {
+				p.startTag(tok, elem, selfClosing)
+			}
 		} else {
 			p.addError(p.peekToken, "unexpected token: %q", p.peekToken.Type)
+			p.nextToken()
 		}
-	case lexer.TokenText:
-		n = &html.Node{Type: html.NodeText, TextContent: p.curToken.Literal}
+	case lexer.TokenText, lexer.TokenCDATA:
+		p.insertText(p.curToken.Literal)
+		p.nextToken()
+	case lexer.TokenComment:
+		p.insertNode(&html.Node{Type: html.NodeComment, TextContent: p.curToken.Literal})
 		p.nextToken()
-
-	case lexer.TokenEOF:
-		if len(p.elements) != 0 {
-			p.addError(p.curToken, "unexpected end of file")
-		}
 	default:
 		p.addError(p.curToken, "unexpected token: %q", p.curToken.Type)
+		p.nextToken()
 	}
-	return
 }
 
-// parseElement parses element node.
-func (p *Parser) parseElement() *html.Node {
-	// keep start token for error
-	startToken := p.curToken
+// parseElement parses an opening element like: `<node attr="value">` or `<node/>`.
+// The content of the element is not parsed.
+func (p *Parser) parseElement() (elem *html.Node, selfClosing bool) {
 	if !p.expectsPeek(lexer.TokenIdent) {
-		return nil
+		return nil, false
 	}
 
-	elem := &html.Node{
+	elem = &html.Node{
 		Type:       html.NodeElement,
-		Tag:        p.curToken.Literal,
+		Tag:        strings.ToLower(p.curToken.Literal),
 		Attributes: map[string]string{},
 	}
 
@@ -131,67 +136,80 @@ func (p *Parser) parseElement() *html.Node {
 	for p.peekTokenIs(lexer.TokenIdent) {
 		p.nextToken()
 		name := p.curToken.Literal
+		// attributes without value are empty: <input disabled>
+		elem.Attributes[name] = ""
 		if !p.peekTokenIs(lexer.TokenEqual) {
 			continue
 		}
 		p.nextToken()
-		if !p.peekTokenIs(lexer.TokenString) {
+		if !p.peekTokenIs(lexer.TokenString) && !p.peekTokenIs(lexer.TokenIdent) {
 			continue
 		}
 		p.nextToken()
 		elem.Attributes[name] = p.curToken.Literal
 	}
 
-	//TODO: handle autoclose
+	// self-closing element: <br/>
+	if p.peekTokenIs(lexer.TokenSlash) {
+		p.nextToken()
+		selfClosing = true
+	}
 
 	// look for closing bracket
 	if !p.expectsPeek(lexer.TokenRBracket) {
-		return nil
+		return nil, false
 	}
 	// skip RBracket
 	p.nextToken()
 
-	// parse inner nodes
-	p.elements = append(p.elements, elem)
-	for {
-		n := p.parseNode()
-		if n == nil {
-			p.addError(startToken, "missing closing element")
-			break
-		}
-		if n.Type == nodeClosingElement {
-			last := p.elements[len(p.elements)-1]
-			if last.Tag != n.Tag {
-				p.addError(startToken, "unexpected closing element. expected=%q got=%q", last.Tag, n.Tag)
-			}
-			p.elements = p.elements[:len(p.elements)-1]
-			break
-		}
-		elem.AddChild(n)
-	}
+	return elem, selfClosing
+}
 
-	return elem
+// voidElements are the elements which cannot have any content.
+// See https://html.spec.whatwg.org/#void-elements
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+
+	// legacy elements, parsed as void elements
+	"basefont": true,
+	"bgsound":  true,
+	"frame":    true,
+	"keygen":   true,
+}
+
+func isVoidElement(tag string) bool {
+	return voidElements[tag]
 }
 
 // parseClosingElement parses elements like: `</node>`
-func (p *Parser) parseClosingElement() *html.Node {
+func (p *Parser) parseClosingElement() (tag string, ok bool) {
 	if !p.expectsPeek(lexer.TokenSlash) {
-		return nil
+		return "", false
 	}
 	if !p.expectsPeek(lexer.TokenIdent) {
-		return nil
-	}
-	n := &html.Node{
-		Type: nodeClosingElement,
-		Tag:  p.curToken.Literal,
+		return "", false
 	}
+	tag = strings.ToLower(p.curToken.Literal)
 
 	if !p.expectsPeek(lexer.TokenRBracket) {
-		return nil
+		return "", false
 	}
 	// skip RBracket
 	p.nextToken()
-	return n
+	return tag, true
 }
 
 func (p *Parser) curTokenIs(t lexer.TokenType) bool {
@@ -224,3 +224,229 @@ func TestParseText(t *testing.T) {
 		t.Fatal("fail to compare nodes")
 	}
 }
+
+func TestParseDoctype(t *testing.T) {
+	tests := []struct {
+		input   string
+		doctype *html.Doctype
+		mode    html.QuirksMode
+	}{
+		{`<a></a>`, nil, html.Quirks},
+		{`<!DOCTYPE html><a></a>`, &html.Doctype{Name: "html"}, html.NoQuirks},
+		{
+			`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN"><a></a>`,
+			&html.Doctype{Name: "html", PublicID: "-//W3C//DTD HTML 4.01 Transitional//EN"},
+			html.Quirks,
+		},
+		{
+			`<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd"><a></a>`,
+			&html.Doctype{
+				Name:     "html",
+				PublicID: "-//W3C//DTD HTML 4.01 Transitional//EN",
+				SystemID: "http://www.w3.org/TR/html4/loose.dtd",
+			},
+			html.LimitedQuirks,
+		},
+		{
+			`<!doctype html system "about:legacy-compat"><a></a>`,
+			&html.Doctype{Name: "html", SystemID: "about:legacy-compat"},
+			html.NoQuirks,
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		parsed := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: unexpected errors %v", tt.input, p.Errors())
+		}
+		if tt.doctype == nil && parsed.Doctype != nil || tt.doctype != nil && *tt.doctype != *parsed.Doctype {
+			t.Errorf("%s: bad doctype. expected=%v got=%v", tt.input, tt.doctype, parsed.Doctype)
+		}
+		if parsed.QuirksMode != tt.mode {
+			t.Errorf("%s: bad mode. expected=%q got=%q", tt.input, tt.mode, parsed.QuirksMode)
+		}
+	}
+}
+
+func TestParseComment(t *testing.T) {
+	expected := &html.Node{Type: html.NodeElement, Tag: "html"}
+	{
+		expected.AddChild(&html.Node{Type: html.NodeComment, TextContent: " first "})
+		expected.AddChild(&html.Node{Type: html.NodeElement, Tag: "head"})
+		body := &html.Node{Type: html.NodeElement, Tag: "body"}
+		a := &html.Node{Type: html.NodeElement, Tag: "a"}
+		a.AddChild(&html.Node{Type: html.NodeComment, TextContent: " inner "})
+		a.AddChild(&html.Node{Type: html.NodeText, TextContent: "1 < 2"})
+		body.AddChild(a)
+		expected.AddChild(body)
+	}
+
+	input := "<!DOCTYPE html>\n<!-- first -->\n<a><!-- inner --><![CDATA[1 < 2]]></a>\n"
+	l := lexer.New(input)
+	p := New(l)
+
+	parsed := p.Parse()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+	ok := compareNodes(expected, parsed, func(a, b *html.Node) {
+		if b.Type != a.Type {
+			t.Fatalf("invalid type. expected=%q got=%q", a.Type, b.Type)
+		}
+		if b.TextContent != a.TextContent {
+			t.Fatalf("invalid text content. expected=%q got=%q", a.TextContent, b.TextContent)
+		}
+	})
+	if !ok {
+		t.Fatal("fail to compare nodes")
+	}
+}
+
+func TestParseElement_void(t *testing.T) {
+	expected := &html.Node{Type: html.NodeElement, Tag: "html"}
+	{
+		expected.AddChild(&html.Node{Type: html.NodeElement, Tag: "head"})
+		body := &html.Node{Type: html.NodeElement, Tag: "body"}
+		div := &html.Node{Type: html.NodeElement, Tag: "div"}
+		div.AddChild(&html.Node{Type: html.NodeText, TextContent: "a"})
+		div.AddChild(&html.Node{Type: html.NodeElement, Tag: "br"})
+		div.AddChild(&html.Node{Type: html.NodeElement, Tag: "img", Attributes: map[string]string{"src": "x"}})
+		div.AddChild(&html.Node{Type: html.NodeElement, Tag: "hr"})
+		div.AddChild(&html.Node{Type: html.NodeElement, Tag: "input", Attributes: map[string]string{"disabled": ""}})
+		div.AddChild(&html.Node{Type: html.NodeElement, Tag: "span"})
+		div.AddChild(&html.Node{Type: html.NodeText, TextContent: "b"})
+		body.AddChild(div)
+		expected.AddChild(body)
+	}
+
+	input := `<div>a<br><img src="x"><hr/><input disabled /><span/>b</div>`
+	l := lexer.New(input)
+	p := New(l)
+
+	parsed := p.Parse()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+	ok := compareNodes(expected, parsed, func(a, b *html.Node) {
+		if b.Tag != a.Tag {
+			t.Fatalf("invalid tag. expected=%q got=%q", a.Tag, b.Tag)
+		}
+		if b.TextContent != a.TextContent {
+			t.Fatalf("invalid text content. expected=%q got=%q", a.TextContent, b.TextContent)
+		}
+		for k, v := range a.Attributes {
+			if vv, ok := b.Attributes[k]; !ok || v != vv {
+				t.Fatalf("bad attribute %q. expected=%q got=%q", k, v, vv)
+			}
+		}
+	})
+	if !ok {
+		t.Fatal("fail to compare nodes")
+	}
+}
+
+// render serializes the children of n, closing every element explicitly.
+func render(n *html.Node) string {
+	var s string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.NodeElement:
+			s += "<" + c.Tag + ">" + render(c) + "</" + c.Tag + ">"
+		case html.NodeText:
+			s += c.TextContent
+		case html.NodeComment:
+			s += "<!--" + c.TextContent + "-->"
+		}
+	}
+	return s
+}
+
+func TestParse_recovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		isErr    bool
+	}{
+		{"implied </p>", `<p>a<p>b`, `<p>a</p><p>b</p>`, false},
+		{"block closes p", `<p>a<div>b</div>`, `<p>a</p><div>b</div>`, false},
+		{"implied </li>", `<ul><li>a<li>b</ul>`, `<ul><li>a</li><li>b</li></ul>`, false},
+		{"implied </dd>", `<dl><dt>a<dd>b<dt>c</dl>`, `<dl><dt>a</dt><dd>b</dd><dt>c</dt></dl>`, false},
+		{"nested headings", `<h1>a<h2>b`, `<h1>a</h1><h2>b</h2>`, true},
+		{"stray closing element", `<div></span>x</div>`, `<div>x</div>`, true},
+		{"unclosed element", `<div><span>x</div>y`, `<div><span>x</span></div>y`, true},
+		{"</p> without <p>", `<div></p></div>`, `<div><p></p></div>`, true},
+		{"misnested formatting", `<b>1<i>2</b>3</i>`, `<b>1<i>2</i></b><i>3</i>`, true},
+		{"adoption agency", `<b>1<p>2</b>3</p>`, `<b>1</b><p><b>2</b>3</p>`, true},
+		{"formatting reopened", `<p><b>a<p>b`, `<p><b>a</b></p><p><b>b</b></p>`, true},
+		{"nested links", `<a>1<a>2`, `<a>1</a><a>2</a>`, true},
+		{"implied tbody", `<table><tr><td>a</td></tr></table>`, `<table><tbody><tr><td>a</td></tr></tbody></table>`, false},
+		{"implied tr and cells", `<table><td>a<td>b<tr><td>c</table>`, `<table><tbody><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></tbody></table>`, false},
+		{"foster parenting", `<table>x<tr><td>y</table>`, `x<table><tbody><tr><td>y</td></tr></tbody></table>`, false},
+		{"cell outside table", `<td>a`, `a`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input))
+			parsed := p.Parse()
+			if (len(p.Errors()) > 0) != tt.isErr {
+				t.Errorf("unexpected errors: %v", p.Errors())
+			}
+			body := parsed.LastChild
+			if got := render(body); got != tt.expected {
+				t.Errorf("bad tree. expected=%s got=%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParse_head(t *testing.T) {
+	input := `<title>Page</title><meta charset="utf-8"><p>text</p>`
+	p := New(lexer.New(input))
+	parsed := p.Parse()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+	expected := `<head><title>Page</title><meta></meta></head><body><p>text</p></body>`
+	if got := render(parsed); got != expected {
+		t.Errorf("bad tree. expected=%s got=%s", expected, got)
+	}
+}
+
+func TestParse_rawText(t *testing.T) {
+	input := `<style>p > a { color: red }</style><p><textarea>a <b> &amp; c</textarea><script>if (a<b) {}</script>`
+	p := New(lexer.New(input))
+	parsed := p.Parse()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	expected := `<head><style>p > a { color: red }</style></head><body><p><textarea>a <b> & c</textarea><script>if (a<b) {}</script></p></body>`
+	if got := render(parsed); got != expected {
+		t.Errorf("bad tree. expected=%s got=%s", expected, got)
+	}
+	for _, tag := range []string{"style", "textarea", "script"} {
+		n := find(parsed, tag)
+		if n == nil || n.FirstChild == nil || n.FirstChild != n.LastChild || n.FirstChild.Type != html.NodeText {
+			t.Errorf("<%s> should have a single text child", tag)
+		}
+	}
+}
+
+// find returns the first element named tag in the tree rooted at n.
+func find(n *html.Node, tag string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.NodeElement {
+			continue
+		}
+		if c.Tag == tag {
+			return c
+		}
+		if found := find(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,738 @@
+package parser
+
+import (
+	"github.com/lysrt/bro/html"
+	"github.com/lysrt/bro/html/lexer"
+)
+
+// insertionMode tells where the parser stands in the document.
+// It is a simplified version of https://html.spec.whatwg.org/#the-insertion-mode
+type insertionMode int
+
+const (
+	initial insertionMode = iota
+	beforeHead
+	inHead
+	afterHead
+	inBody
+)
+
+// headElements are the elements which belong in <head> when they come before the body.
+var headElements = map[string]bool{
+	"base":     true,
+	"basefont": true,
+	"bgsound":  true,
+	"link":     true,
+	"meta":     true,
+	"noframes": true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"title":    true,
+}
+
+// closesParagraph are the elements whose opening element implies </p>.
+var closesParagraph = map[string]bool{
+	"address":    true,
+	"article":    true,
+	"aside":      true,
+	"blockquote": true,
+	"center":     true,
+	"details":    true,
+	"dialog":     true,
+	"dir":        true,
+	"div":        true,
+	"dl":         true,
+	"fieldset":   true,
+	"figcaption": true,
+	"figure":     true,
+	"footer":     true,
+	"form":       true,
+	"header":     true,
+	"hgroup":     true,
+	"hr":         true,
+	"listing":    true,
+	"main":       true,
+	"menu":       true,
+	"nav":        true,
+	"ol":         true,
+	"p":          true,
+	"pre":        true,
+	"search":     true,
+	"section":    true,
+	"summary":    true,
+	"ul":         true,
+	"h1":         true,
+	"h2":         true,
+	"h3":         true,
+	"h4":         true,
+	"h5":         true,
+	"h6":         true,
+}
+
+var headings = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// impliedEndTags are the elements closed implicitly by the closing of their parent.
+var impliedEndTags = map[string]bool{
+	"dd":       true,
+	"dt":       true,
+	"li":       true,
+	"optgroup": true,
+	"option":   true,
+	"p":        true,
+	"rb":       true,
+	"rp":       true,
+	"rt":       true,
+	"rtc":      true,
+}
+
+// optionalEndTags are the elements which may still be open at the end of the document.
+var optionalEndTags = map[string]bool{
+	"body":  true,
+	"html":  true,
+	"tbody": true,
+	"td":    true,
+	"tfoot": true,
+	"th":    true,
+	"thead": true,
+	"tr":    true,
+}
+
+// markerElements insert a marker in the list of active formatting elements,
+// so that formatting elements do not leak in or out of them.
+var markerElements = map[string]bool{
+	"applet":  true,
+	"caption": true,
+	"marquee": true,
+	"object":  true,
+	"td":      true,
+	"th":      true,
+}
+
+// tableElements are the elements which may only contain table parts.
+// Any other content is moved before the table ("foster parenting").
+var tableElements = map[string]bool{
+	"table": true,
+	"tbody": true,
+	"tfoot": true,
+	"thead": true,
+	"tr":    true,
+}
+
+// tableContent are the elements allowed as direct content of tableElements.
+var tableContent = map[string]bool{
+	"caption":  true,
+	"col":      true,
+	"colgroup": true,
+	"form":     true,
+	"script":   true,
+	"style":    true,
+	"table":    true,
+	"tbody":    true,
+	"td":       true,
+	"template": true,
+	"tfoot":    true,
+	"th":       true,
+	"thead":    true,
+	"tr":       true,
+}
+
+// specialElements are the elements which stop the search of a matching opening element.
+// See https://html.spec.whatwg.org/#special
+var specialElements = map[string]bool{
+	"address": true, "applet": true, "area": true, "article": true, "aside": true,
+	"base": true, "basefont": true, "bgsound": true, "blockquote": true, "body": true,
+	"br": true, "button": true, "caption": true, "center": true, "col": true,
+	"colgroup": true, "dd": true, "details": true, "dir": true, "div": true,
+	"dl": true, "dt": true, "embed": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "frame": true, "frameset": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"head": true, "header": true, "hgroup": true, "hr": true, "html": true,
+	"iframe": true, "img": true, "input": true, "keygen": true, "li": true,
+	"link": true, "listing": true, "main": true, "marquee": true, "menu": true,
+	"meta": true, "nav": true, "noembed": true, "noframes": true, "noscript": true,
+	"object": true, "ol": true, "p": true, "param": true, "plaintext": true,
+	"pre": true, "script": true, "search": true, "section": true, "select": true,
+	"source": true, "style": true, "summary": true, "table": true, "tbody": true,
+	"td": true, "template": true, "textarea": true, "tfoot": true, "th": true,
+	"thead": true, "title": true, "tr": true, "track": true, "ul": true,
+	"wbr": true, "xmp": true,
+}
+
+// Scopes are the elements stopping the search of an open element.
+// See https://html.spec.whatwg.org/#has-an-element-in-scope
+var (
+	defaultScope = map[string]bool{
+		"applet":   true,
+		"caption":  true,
+		"html":     true,
+		"marquee":  true,
+		"object":   true,
+		"table":    true,
+		"td":       true,
+		"template": true,
+		"th":       true,
+	}
+	listItemScope = union(defaultScope, "ol", "ul")
+	buttonScope   = union(defaultScope, "button")
+	tableScope    = map[string]bool{"html": true, "table": true, "template": true}
+)
+
+func union(set map[string]bool, tags ...string) map[string]bool {
+	u := make(map[string]bool, len(set)+len(tags))
+	for tag := range set {
+		u[tag] = true
+	}
+	for _, tag := range tags {
+		u[tag] = true
+	}
+	return u
+}
+
+// startTag adds an opening element to the tree.
+func (p *Parser) startTag(tok lexer.Token, elem *html.Node, selfClosing bool) {
+	switch elem.Tag {
+	case "html":
+		mergeAttributes(p.root, elem)
+		if p.mode == initial {
+			p.mode = beforeHead
+		}
+		return
+	case "head":
+		if p.head != nil {
+			p.addError(tok, "unexpected head element")
+			return
+		}
+		p.head = elem
+		p.root.AddChild(elem)
+		p.elements = append(p.elements, elem)
+		p.mode = inHead
+		return
+	case "body":
+		if p.body != nil {
+			p.addError(tok, "unexpected body element")
+			mergeAttributes(p.body, elem)
+			return
+		}
+		p.closeHead()
+		p.body = elem
+		p.root.AddChild(elem)
+		p.elements = append(p.elements, elem)
+		p.mode = inBody
+		return
+	}
+
+	if p.mode < inBody {
+		if headElements[elem.Tag] {
+			p.openHead()
+			p.insertElement(elem, selfClosing)
+			return
+		}
+		p.openBody()
+	}
+	p.startTagInBody(tok, elem, selfClosing)
+}
+
+// startTagInBody adds an opening element to the body,
+// closing the elements it implicitly ends.
+func (p *Parser) startTagInBody(tok lexer.Token, elem *html.Node, selfClosing bool) {
+	tag := elem.Tag
+	switch {
+	case tag == "li" || tag == "dd" || tag == "dt":
+		// A list item closes the previous one: <li>a<li>b
+		for i := len(p.elements) - 1; i >= 0; i-- {
+			node := p.elements[i]
+			if node.Tag == tag || (tag != "li" && (node.Tag == "dd" || node.Tag == "dt")) {
+				p.generateImpliedEndTags(node.Tag)
+				if p.currentNode() != node {
+					p.addError(tok, "unexpected %q element", tag)
+				}
+				p.popUntil(node.Tag)
+				break
+			}
+			if specialElements[node.Tag] && node.Tag != "address" && node.Tag != "div" && node.Tag != "p" {
+				break
+			}
+		}
+		p.closeParagraphInScope()
+		p.insertElement(elem, selfClosing)
+
+	case headings[tag]:
+		p.closeParagraphInScope()
+		if headings[p.currentNode().Tag] {
+			p.addError(tok, "unexpected %q element in heading", tag)
+			p.pop()
+		}
+		p.insertElement(elem, selfClosing)
+
+	case tag == "table":
+		if quirksMode(p.doctype) != html.Quirks {
+			p.closeParagraphInScope()
+		}
+		if tableElements[p.currentNode().Tag] {
+			// A table directly in a table closes it
+			p.addError(tok, "unexpected table element in table")
+			p.popUntil("table")
+		}
+		p.insertElement(elem, selfClosing)
+
+	case closesParagraph[tag]:
+		p.closeParagraphInScope()
+		p.insertElement(elem, selfClosing)
+
+	case tag == "button":
+		if p.inScope(defaultScope, "button") {
+			p.addError(tok, "unexpected button element in button")
+			p.generateImpliedEndTags("")
+			p.popUntil("button")
+		}
+		p.reconstructFormatting()
+		p.insertElement(elem, selfClosing)
+
+	case tag == "a":
+		if a := p.formattingElement("a"); a != nil {
+			p.addError(tok, "unexpected a element in a")
+			p.adoptionAgency("a")
+			p.removeFormatting(a)
+			p.removeElement(a)
+		}
+		p.reconstructFormatting()
+		p.insertElement(elem, selfClosing)
+		p.formatting = append(p.formatting, elem)
+
+	case tag == "nobr":
+		p.reconstructFormatting()
+		if p.inScope(defaultScope, "nobr") {
+			p.addError(tok, "unexpected nobr element in nobr")
+			p.adoptionAgency("nobr")
+			p.reconstructFormatting()
+		}
+		p.insertElement(elem, selfClosing)
+		p.formatting = append(p.formatting, elem)
+
+	case formattingElements[tag]:
+		p.reconstructFormatting()
+		p.insertElement(elem, selfClosing)
+		p.formatting = append(p.formatting, elem)
+
+	case tag == "applet" || tag == "marquee" || tag == "object":
+		p.reconstructFormatting()
+		p.insertElement(elem, selfClosing)
+		p.formatting = append(p.formatting, nil)
+
+	case tag == "option" || tag == "optgroup":
+		if p.currentNode().Tag == "option" {
+			p.pop()
+		}
+		p.reconstructFormatting()
+		p.insertElement(elem, selfClosing)
+
+	case tag == "image":
+		// <image> is an old alias of <img>
+		elem.Tag = "img"
+		p.reconstructFormatting()
+		p.insertElement(elem, selfClosing)
+
+	case tableContent[tag] && tag != "form" && !headElements[tag]:
+		if !p.inScope(tableScope, "table") {
+			p.addError(tok, "unexpected %q element outside of table", tag)
+			return
+		}
+		p.startTableTag(elem, selfClosing)
+
+	default:
+		p.reconstructFormatting()
+		p.insertElement(elem, selfClosing)
+	}
+}
+
+// startTableTag adds a table part, inserting the implied <tbody> and <tr> elements.
+func (p *Parser) startTableTag(elem *html.Node, selfClosing bool) {
+	switch elem.Tag {
+	case "caption", "colgroup":
+		p.closeCell()
+		p.clearBackTo("table")
+		p.insertElement(elem, selfClosing)
+		if elem.Tag == "caption" {
+			p.formatting = append(p.formatting, nil)
+		}
+	case "col":
+		if p.currentNode().Tag != "colgroup" {
+			p.closeCell()
+			p.clearBackTo("table")
+			p.insertElement(&html.Node{Type: html.NodeElement, Tag: "colgroup"}, false)
+		}
+		p.insertElement(elem, true)
+	case "tbody", "thead", "tfoot":
+		p.closeCell()
+		p.clearBackTo("table")
+		p.insertElement(elem, selfClosing)
+	case "tr":
+		p.closeCell()
+		p.clearBackTo("tbody", "thead", "tfoot", "table")
+		if p.currentNode().Tag == "table" {
+			p.insertElement(&html.Node{Type: html.NodeElement, Tag: "tbody"}, false)
+		}
+		p.insertElement(elem, selfClosing)
+	case "td", "th":
+		p.closeCell()
+		p.clearBackTo("tr", "tbody", "thead", "tfoot", "table")
+		if p.currentNode().Tag == "table" {
+			p.insertElement(&html.Node{Type: html.NodeElement, Tag: "tbody"}, false)
+		}
+		if p.currentNode().Tag != "tr" {
+			p.insertElement(&html.Node{Type: html.NodeElement, Tag: "tr"}, false)
+		}
+		p.insertElement(elem, selfClosing)
+		p.formatting = append(p.formatting, nil)
+	}
+}
+
+// endTag closes an element, and the elements it implicitly ends.
+func (p *Parser) endTag(tok lexer.Token, tag string) {
+	switch tag {
+	case "html", "body":
+		p.openBody()
+		return
+	case "head":
+		if p.mode != inHead {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+		p.closeHead()
+		return
+	}
+
+	if p.mode < inBody {
+		if p.mode == inHead && p.currentNode() != p.head && p.currentNode().Tag == tag {
+			// closing a head element, like </title>
+			p.pop()
+			return
+		}
+		if tag != "br" {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+		p.openBody()
+	}
+	p.endTagInBody(tok, tag)
+}
+
+// endTagInBody closes an element of the body.
+func (p *Parser) endTagInBody(tok lexer.Token, tag string) {
+	switch {
+	case tag == "p":
+		if !p.inScope(buttonScope, "p") {
+			// </p> without <p> creates an empty paragraph
+			p.addError(tok, "unexpected closing element %q", tag)
+			p.insertElement(&html.Node{Type: html.NodeElement, Tag: "p"}, false)
+		}
+		p.closeParagraph()
+
+	case tag == "li" || tag == "dd" || tag == "dt":
+		scope := defaultScope
+		if tag == "li" {
+			scope = listItemScope
+		}
+		if !p.inScope(scope, tag) {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+		p.generateImpliedEndTags(tag)
+		p.popUntil(tag)
+
+	case headings[tag]:
+		if !p.inScope(defaultScope, "h1", "h2", "h3", "h4", "h5", "h6") {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+		p.generateImpliedEndTags("")
+		if p.currentNode().Tag != tag {
+			p.addError(tok, "unexpected closing element %q", tag)
+		}
+		p.popUntil("h1", "h2", "h3", "h4", "h5", "h6")
+
+	case tag == "a" || tag == "nobr" || formattingElements[tag]:
+		if !p.adoptionAgency(tag) {
+			p.closeElement(tok, tag)
+		}
+
+	case tag == "br":
+		// </br> is parsed as <br>
+		p.addError(tok, "unexpected closing element %q", tag)
+		p.reconstructFormatting()
+		p.insertElement(&html.Node{Type: html.NodeElement, Tag: "br", Attributes: map[string]string{}}, true)
+
+	case tag == "table" || tag == "tbody" || tag == "thead" || tag == "tfoot" ||
+		tag == "tr" || tag == "caption" || tag == "colgroup":
+		if !p.inScope(tableScope, tag) {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+		// The open table parts and cells are closed as well
+		p.popUntil(tag)
+
+	case tag == "td" || tag == "th":
+		if !p.inScope(tableScope, tag) {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+		p.generateImpliedEndTags(tag)
+		if p.currentNode().Tag != tag {
+			p.addError(tok, "unexpected closing element %q", tag)
+		}
+		p.popUntil(tag)
+
+	case closesParagraph[tag] || markerElements[tag] || tag == "button":
+		if !p.inScope(defaultScope, tag) {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+		p.generateImpliedEndTags("")
+		if p.currentNode().Tag != tag {
+			p.addError(tok, "unexpected closing element %q", tag)
+		}
+		p.popUntil(tag)
+
+	default:
+		p.closeElement(tok, tag)
+	}
+}
+
+// closeElement closes the nearest open element named tag,
+// unless a special element is open in between.
+func (p *Parser) closeElement(tok lexer.Token, tag string) {
+	for i := len(p.elements) - 1; i >= 0; i-- {
+		node := p.elements[i]
+		if node.Tag == tag {
+			p.generateImpliedEndTags(tag)
+			if p.currentNode() != node {
+				p.addError(tok, "unexpected closing element. expected=%q got=%q", p.currentNode().Tag, tag)
+			}
+			for p.pop() != node {
+			}
+			return
+		}
+		if specialElements[node.Tag] {
+			p.addError(tok, "unexpected closing element %q", tag)
+			return
+		}
+	}
+}
+
+// insertText adds a text node to the current node.
+func (p *Parser) insertText(text string) {
+	if p.mode < inBody && (p.mode != inHead || p.currentNode() == p.head) {
+		p.openBody()
+	}
+	if p.mode == inBody {
+		p.reconstructFormatting()
+	}
+	p.insertNode(&html.Node{Type: html.NodeText, TextContent: text})
+}
+
+// insertElement adds an element to the current node.
+// Unless it is void or self-closing, it becomes the new current node.
+func (p *Parser) insertElement(elem *html.Node, selfClosing bool) {
+	p.insertNode(elem)
+	if selfClosing || isVoidElement(elem.Tag) {
+		return
+	}
+	p.elements = append(p.elements, elem)
+}
+
+// insertNode appends n to the current node.
+// Content which is not allowed in a table is moved just before the table.
+func (p *Parser) insertNode(n *html.Node) {
+	current := p.currentNode()
+	if !tableElements[current.Tag] || (n.Type == html.NodeElement && tableContent[n.Tag]) || n.Type == html.NodeComment {
+		current.AddChild(n)
+		return
+	}
+
+	for i := len(p.elements) - 1; i > 0; i-- {
+		table := p.elements[i]
+		if table.Tag != "table" {
+			continue
+		}
+		if table.Parent != nil {
+			table.Parent.InsertBefore(n, table)
+		} else {
+			p.elements[i-1].AddChild(n)
+		}
+		return
+	}
+	current.AddChild(n)
+}
+
+// openHead makes sure the current node is the head element.
+func (p *Parser) openHead() {
+	if p.mode == inHead {
+		return
+	}
+	if p.head == nil {
+		p.head = &html.Node{Type: html.NodeElement, Tag: "head", Attributes: map[string]string{}}
+		p.root.AddChild(p.head)
+	}
+	p.elements = append(p.elements, p.head)
+	p.mode = inHead
+}
+
+// closeHead closes the head element, creating it if it is missing.
+func (p *Parser) closeHead() {
+	if p.head == nil {
+		p.openHead()
+	}
+	if p.mode == inHead {
+		p.popUntil("head")
+		p.mode = afterHead
+	}
+}
+
+// openBody creates the body element if it is missing.
+func (p *Parser) openBody() {
+	if p.body != nil {
+		return
+	}
+	p.closeHead()
+	p.body = &html.Node{Type: html.NodeElement, Tag: "body", Attributes: map[string]string{}}
+	p.root.AddChild(p.body)
+	p.elements = append(p.elements, p.body)
+	p.mode = inBody
+}
+
+// endOfFile closes all the open elements.
+func (p *Parser) endOfFile() {
+	p.openBody()
+	for _, e := range p.elements {
+		if !optionalEndTags[e.Tag] && !impliedEndTags[e.Tag] {
+			p.addError(p.curToken, "unexpected end of file, missing closing element %q", e.Tag)
+			break
+		}
+	}
+	p.elements = p.elements[:1]
+}
+
+func (p *Parser) currentNode() *html.Node {
+	return p.elements[len(p.elements)-1]
+}
+
+// pop removes the current node from the stack of open elements and returns it.
+func (p *Parser) pop() *html.Node {
+	n := p.currentNode()
+	if len(p.elements) == 1 {
+		// never pop the root element
+		return n
+	}
+	p.elements = p.elements[:len(p.elements)-1]
+	if markerElements[n.Tag] {
+		p.clearFormattingToMarker()
+	}
+	return n
+}
+
+// popUntil pops elements until one of the tags has been popped.
+func (p *Parser) popUntil(tags ...string) {
+	for len(p.elements) > 1 {
+		n := p.pop()
+		for _, tag := range tags {
+			if n.Tag == tag {
+				return
+			}
+		}
+	}
+}
+
+// clearBackTo pops elements until the current node is one of tags.
+func (p *Parser) clearBackTo(tags ...string) {
+	for len(p.elements) > 1 {
+		current := p.currentNode().Tag
+		if current == "html" || current == "template" {
+			return
+		}
+		for _, tag := range tags {
+			if current == tag {
+				return
+			}
+		}
+		p.pop()
+	}
+}
+
+// inScope reports whether one of tags is open, without crossing an element of scope.
+func (p *Parser) inScope(scope map[string]bool, tags ...string) bool {
+	for i := len(p.elements) - 1; i >= 0; i-- {
+		node := p.elements[i]
+		for _, tag := range tags {
+			if node.Tag == tag {
+				return true
+			}
+		}
+		if scope[node.Tag] {
+			return false
+		}
+	}
+	return false
+}
+
+// generateImpliedEndTags closes the elements with an optional end tag, except the given one.
+func (p *Parser) generateImpliedEndTags(except string) {
+	for {
+		tag := p.currentNode().Tag
+		if !impliedEndTags[tag] || tag == except {
+			return
+		}
+		p.pop()
+	}
+}
+
+// closeParagraph closes the current paragraph.
+func (p *Parser) closeParagraph() {
+	p.generateImpliedEndTags("p")
+	p.popUntil("p")
+}
+
+// closeParagraphInScope closes the current paragraph, if any: <p>a<div>b</div> is <p>a</p><div>b</div>
+func (p *Parser) closeParagraphInScope() {
+	if p.inScope(buttonScope, "p") {
+		p.closeParagraph()
+	}
+}
+
+// closeCell closes the current table cell, if any.
+func (p *Parser) closeCell() {
+	if !p.inScope(tableScope, "td", "th") {
+		return
+	}
+	p.generateImpliedEndTags("")
+	p.popUntil("td", "th")
+}
+
+// removeElement removes n from the stack of open elements.
+func (p *Parser) removeElement(n *html.Node) {
+	if i := indexOf(p.elements, n); i > 0 {
+		p.elements = append(p.elements[:i], p.elements[i+1:]...)
+	}
+}
+
+func mergeAttributes(dst, src *html.Node) {
+	if dst.Attributes == nil {
+		dst.Attributes = map[string]string{}
+	}
+	for k, v := range src.Attributes {
+		if _, ok := dst.Attributes[k]; !ok {
+			dst.Attributes[k] = v
+		}
+	}
+}
+
+func indexOf(nodes []*html.Node, n *html.Node) int {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if nodes[i] == n {
+			return i
+		}
+	}
+	return -1
+}
@@ -50,7 +50,7 @@ func NodeChildren(n *Node) []*Node {
 // NodeFirstElementChild returns the first element child of the node.
 func NodeFirstElementChild(n *Node) *Node {
 	for e := n.FirstChild; e != nil; e = e.NextSibling {
-		if e.Type == NodeText {
+		if e.Type != NodeElement {
 			continue
 		}
 		return e
@@ -61,7 +61,7 @@ func NodeFirstElementChild(n *Node) *Node {
 // NodeLastElementChild returns the last element child of the node.
 func NodeLastElementChild(n *Node) *Node {
 	for e := n.LastChild; e != nil; e = e.PrevSibling {
-		if e.Type == NodeText {
+		if e.Type != NodeElement {
 			continue
 		}
 		return e
@@ -72,7 +72,7 @@ func NodeLastElementChild(n *Node) *Node {
 // NodeNextElementSibling returns the next element sibling of the node.
 func NodeNextElementSibling(n *Node) *Node {
 	for e := n.NextSibling; e != nil; e = e.NextSibling {
-		if e.Type == NodeText {
+		if e.Type != NodeElement {
 			continue
 		}
 		return e
@@ -83,7 +83,7 @@ func NodeNextElementSibling(n *Node) *Node {
 // NodePrevElementSibling returns the previous element sibling of the node.
 func NodePrevElementSibling(n *Node) *Node {
 	for e := n.PrevSibling; e != nil; e = e.PrevSibling {
-		if e.Type == NodeText {
+		if e.Type != NodeElement {
 			continue
 		}
 		return e
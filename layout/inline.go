@@ -0,0 +1,374 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/font"
+	"github.com/lysrt/bro/html"
+	"github.com/lysrt/bro/style"
+)
+
+const (
+	// defaultFontSize is the font size in pixels used when none is specified
+	defaultFontSize = 16.0
+
+	// normalLineHeight is the line height, relative to the font size, used for line-height: normal
+	normalLineHeight = 1.2
+)
+
+// LineBox is a row of inline content inside an anonymous block
+type LineBox struct {
+	// Rect is the position and size of the line
+	Rect Rect
+
+	// Fragments are the pieces of inline boxes placed on this line, from left to right.
+	// The fragment of an inline element comes before the fragments of its content.
+	Fragments []*Fragment
+}
+
+// Fragment is the part of an inline box that sits on a single line
+type Fragment struct {
+	// Box is the inline box this fragment belongs to
+	Box *LayoutBox
+
+	// Rect is the content area of the fragment
+	Rect Rect
+
+	// Text holds the words of a text fragment, separated by single spaces
+	Text string
+
+	// Face is the font used to measure the fragment, and to draw its text
+	Face *font.Face
+
+	// Baseline is the vertical position of the line baseline
+	Baseline float64
+
+	ascent, descent, leading float64
+}
+
+// layoutInline lays out the inline children of an anonymous block into line boxes.
+// The lines are stacked below the content already in the containing block,
+// and wrap when they would overflow its width.
+func (box *LayoutBox) layoutInline(containingBlock Dimensions, c layoutContext) {
+	d := &box.Dimensions
+	d.Content.X = containingBlock.Content.X
+	d.Content.Y = containingBlock.Content.Y + containingBlock.Content.Height
+	d.Content.Width = containingBlock.Content.Width
+
+	lb := &lineBuilder{
+		left:    d.Content.X,
+		right:   d.Content.X + d.Content.Width,
+		y:       d.Content.Y,
+		context: c,
+	}
+	lb.newLine()
+	for _, child := range box.Children {
+		lb.layoutInlineBox(child)
+	}
+	lb.finishLine()
+
+	// Fragments are only positioned vertically once their line is complete
+	for _, b := range lb.boxes {
+		b.Dimensions.Content = fragmentsBounds(b.Fragments)
+	}
+
+	box.Lines = lb.lines
+	d.Content.Height = lb.y - d.Content.Y
+}
+
+// lineBuilder places inline boxes one after the other,
+// breaking lines when the content would overflow the containing block.
+type lineBuilder struct {
+	left, right float64 // horizontal bounds of the lines
+	x, y        float64 // pen position: x on the current line, y at the top of the line
+
+	line      *LineBox
+	lineStart bool // no word has been placed on the current line yet
+	lines     []*LineBox
+
+	// open holds the current fragment of each inline element entered but not yet left
+	open []*Fragment
+
+	// boxes holds every box which received fragments
+	boxes []*LayoutBox
+
+	context layoutContext
+}
+
+func (lb *lineBuilder) newLine() {
+	lb.line = &LineBox{Rect: Rect{X: lb.left, Y: lb.y, Width: lb.right - lb.left}}
+	lb.x = lb.left
+	lb.lineStart = true
+}
+
+// finishLine aligns the fragments of the current line on a common baseline
+// and moves the pen below it.
+func (lb *lineBuilder) finishLine() {
+	line := lb.line
+	if lb.lineStart {
+		// Nothing was written on this line: it takes no space
+		for _, f := range line.Fragments {
+			f.Baseline = line.Rect.Y
+			f.Rect.Y = line.Rect.Y
+		}
+		return
+	}
+
+	var above, below float64
+	for _, f := range line.Fragments {
+		if a := f.leading + f.ascent; a > above {
+			above = a
+		}
+		if b := f.descent + f.leading; b > below {
+			below = b
+		}
+	}
+	line.Rect.Height = above + below
+
+	baseline := line.Rect.Y + above
+	for _, f := range line.Fragments {
+		f.Baseline = baseline
+		f.Rect.Y = baseline - f.ascent
+		f.Rect.Height = f.ascent + f.descent
+	}
+
+	lb.lines = append(lb.lines, line)
+	lb.y += line.Rect.Height
+}
+
+// breakLine starts a new line, carrying the open inline elements over to it.
+func (lb *lineBuilder) breakLine() {
+	for _, f := range lb.open {
+		f.Rect.Width = lb.x - f.Rect.X
+		if f.Rect.Width == 0 {
+			// Nothing was placed in the element on this line: move it to the next one
+			f.Box.Fragments = f.Box.Fragments[:len(f.Box.Fragments)-1]
+			lb.line.Fragments = removeFragment(lb.line.Fragments, f)
+		}
+	}
+	lb.finishLine()
+	lb.newLine()
+	for i, f := range lb.open {
+		lb.open[i] = lb.addFragment(f.Box, f.Face, f.leading)
+	}
+}
+
+func (lb *lineBuilder) addFragment(box *LayoutBox, face *font.Face, leading float64) *Fragment {
+	f := &Fragment{
+		Box:     box,
+		Rect:    Rect{X: lb.x},
+		Face:    face,
+		ascent:  face.Ascent(),
+		descent: face.Descent(),
+		leading: leading,
+	}
+	if len(box.Fragments) == 0 {
+		lb.boxes = append(lb.boxes, box)
+	}
+	box.Fragments = append(box.Fragments, f)
+	lb.line.Fragments = append(lb.line.Fragments, f)
+	return f
+}
+
+func (lb *lineBuilder) layoutInlineBox(box *LayoutBox) {
+	box.Fragments = nil
+	if box.StyledNode == nil {
+		// The anonymous block of a block box nested in an inline box holds its inline content
+		for _, child := range box.Children {
+			lb.layoutInlineBox(child)
+		}
+		return
+	}
+	if box.StyledNode.Node.Type == html.NodeText {
+		lb.layoutText(box)
+		return
+	}
+	// Block boxes nested in an inline box are laid out inline as well
+	lb.layoutElement(box)
+}
+
+// layoutElement places an inline element and its content.
+// Its horizontal margin, border and padding push the content apart,
+// the vertical ones do not affect the line height.
+func (lb *lineBuilder) layoutElement(box *LayoutBox) {
+	node := box.StyledNode
+	d := &box.Dimensions
+	// percentages refer to the width of the lines
+	lengths := lb.context.lengths(node, lb.right-lb.left)
+
+	d.margin.Left = edgeValue(node, lengths, "margin-left")
+	d.margin.Right = edgeValue(node, lengths, "margin-right")
+	d.margin.Top = edgeValue(node, lengths, "margin-top")
+	d.margin.Bottom = edgeValue(node, lengths, "margin-bottom")
+	d.Border.Left = edgeValue(node, lengths, "border-left-width")
+	d.Border.Right = edgeValue(node, lengths, "border-right-width")
+	d.Border.Top = edgeValue(node, lengths, "border-top-width")
+	d.Border.Bottom = edgeValue(node, lengths, "border-bottom-width")
+	d.padding.Left = edgeValue(node, lengths, "padding-left")
+	d.padding.Right = edgeValue(node, lengths, "padding-right")
+	d.padding.Top = edgeValue(node, lengths, "padding-top")
+	d.padding.Bottom = edgeValue(node, lengths, "padding-bottom")
+
+	lb.x += d.margin.Left + d.Border.Left + d.padding.Left
+
+	face, leading := fontMetrics(node)
+	lb.open = append(lb.open, lb.addFragment(box, face, leading))
+	for _, child := range box.Children {
+		lb.layoutInlineBox(child)
+	}
+	last := lb.open[len(lb.open)-1]
+	lb.open = lb.open[:len(lb.open)-1]
+	last.Rect.Width = lb.x - last.Rect.X
+
+	lb.x += d.padding.Right + d.Border.Right + d.margin.Right
+}
+
+// layoutText places the words of a text node, breaking lines between words.
+func (lb *lineBuilder) layoutText(box *LayoutBox) {
+	node := box.StyledNode
+	face, leading := fontMetrics(node)
+
+	var f *Fragment
+	for _, word := range strings.Fields(node.Node.TextContent) {
+		width := face.Width(word)
+		space := 0.0
+		if !lb.lineStart {
+			space = face.Width(" ")
+		}
+		if !lb.lineStart && lb.x+space+width > lb.right {
+			lb.breakLine()
+			f, space = nil, 0
+		}
+
+		if f == nil {
+			lb.x += space
+			f = lb.addFragment(box, face, leading)
+			f.Text = word
+		} else {
+			lb.x += space
+			f.Text += " " + word
+		}
+		lb.x += width
+		f.Rect.Width = lb.x - f.Rect.X
+		lb.lineStart = false
+	}
+}
+
+func removeFragment(fragments []*Fragment, f *Fragment) []*Fragment {
+	for i := range fragments {
+		if fragments[i] == f {
+			return append(fragments[:i], fragments[i+1:]...)
+		}
+	}
+	return fragments
+}
+
+// fragmentsBounds returns the smallest rectangle containing all the fragments.
+func fragmentsBounds(fragments []*Fragment) Rect {
+	if len(fragments) == 0 {
+		return Rect{}
+	}
+	r := fragments[0].Rect
+	for _, f := range fragments[1:] {
+		x1 := maxFloat(r.X+r.Width, f.Rect.X+f.Rect.Width)
+		y1 := maxFloat(r.Y+r.Height, f.Rect.Y+f.Rect.Height)
+		r.X = minFloat(r.X, f.Rect.X)
+		r.Y = minFloat(r.Y, f.Rect.Y)
+		r.Width = x1 - r.X
+		r.Height = y1 - r.Y
+	}
+	return r
+}
+
+// edgeValue returns the value in pixels of a margin, border or padding longhand of node, or 0.
+func edgeValue(node *style.StyledNode, lengths css.LengthContext, name string) float64 {
+	if value, ok := node.Value(name); ok {
+		return value.ToPx(lengths)
+	}
+	return 0.0
+}
+
+// fontSize returns the font size of a node in pixels.
+func fontSize(node *style.StyledNode) float64 {
+	if value, ok := node.Value("font-size"); ok && value.Length.Unit == css.Px {
+		return value.Length.Quantity
+	}
+	return defaultFontSize
+}
+
+// fontMetrics returns the font face of a node,
+// and the half-leading added above and below the glyphs to fill the line height.
+func fontMetrics(node *style.StyledNode) (face *font.Face, leading float64) {
+	face = fontFace(node)
+
+	size := fontSize(node)
+	lineHeight := size * normalLineHeight
+	if value, ok := node.Value("line-height"); ok {
+		switch value.Length.Unit {
+		case css.Px:
+			lineHeight = value.Length.Quantity
+		case "":
+			// A unitless number is a multiple of the font size
+			if value.Length.Quantity > 0 {
+				lineHeight = value.Length.Quantity * size
+			}
+		}
+	}
+
+	leading = (lineHeight - face.Ascent() - face.Descent()) / 2.0
+	return
+}
+
+// fontFace returns the face matching the font properties of a node.
+func fontFace(node *style.StyledNode) *font.Face {
+	var family, weight, fontStyle string
+	if value, ok := node.Value("font-family"); ok {
+		family = fontFamily(value)
+	}
+	if value, ok := node.Value("font-weight"); ok {
+		weight = value.Keyword
+		if weight == "" {
+			weight = strconv.FormatFloat(value.Length.Quantity, 'f', -1, 64)
+		}
+	}
+	if value, ok := node.Value("font-style"); ok {
+		fontStyle = value.Keyword
+	}
+	return font.Default.Face(family, weight, fontStyle, fontSize(node))
+}
+
+// fontFamily returns a font-family value as text, like `Go Mono, monospace` for `"Go Mono", monospace`.
+func fontFamily(value css.Value) string {
+	if value.List == nil {
+		if value.Text != "" {
+			return value.Text
+		}
+		return value.Keyword
+	}
+
+	separator := " " // the words of an unquoted name, like Go Mono
+	if value.List.Comma {
+		separator = ", "
+	}
+	names := make([]string, len(value.List.Values))
+	for i, v := range value.List.Values {
+		names[i] = fontFamily(v)
+	}
+	return strings.Join(names, separator)
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
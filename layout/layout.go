@@ -29,6 +29,12 @@ type LayoutBox struct {
 
 	// Children of this node, in the layout tree, following the structure of the style tree
 	Children []*LayoutBox
+
+	// Lines holds the line boxes of an anonymous block, top to bottom
+	Lines []*LineBox
+
+	// Fragments holds the pieces of an inline box, one per line it spans
+	Fragments []*Fragment
 }
 
 // Dimensions represents the position, size, margin, padding and border of a layout box
@@ -74,6 +80,24 @@ func (r Rect) expandedBy(edge EdgeSizes) Rect {
 	}
 }
 
+// layoutContext holds what the lengths of every box of the tree can be relative to.
+type layoutContext struct {
+	viewport     Rect
+	rootFontSize float64
+}
+
+// lengths returns the context resolving the lengths of a node,
+// whose percentages refer to the width of its containing block.
+func (c layoutContext) lengths(node *style.StyledNode, containingWidth float64) css.LengthContext {
+	return css.LengthContext{
+		FontSize:       fontSize(node),
+		RootFontSize:   c.rootFontSize,
+		PercentBase:    containingWidth,
+		ViewportWidth:  c.viewport.Width,
+		ViewportHeight: c.viewport.Height,
+	}
+}
+
 func newLayoutBox(boxType BoxType, styledNode *style.StyledNode) *LayoutBox {
 	var children []*LayoutBox
 	return &LayoutBox{
@@ -138,34 +162,49 @@ func (box *LayoutBox) getInlineContainer() *LayoutBox {
 	panic("No more cases to switch")
 }
 
-func (box *LayoutBox) Layout(containingBlock Dimensions) {
+// Layout lays out the tree in the viewport, the initial containing block of the root box.
+// The size of the viewport resolves the vw, vh, vmin and vmax lengths.
+func (box *LayoutBox) Layout(viewport Dimensions) {
+	c := layoutContext{viewport: viewport.Content, rootFontSize: defaultFontSize}
+	if box.StyledNode != nil {
+		c.rootFontSize = fontSize(box.StyledNode)
+	}
+
+	// the height of a containing block is the height of the content already laid out in it
+	viewport.Content.Height = 0
+	box.layout(viewport, c)
+}
+
+func (box *LayoutBox) layout(containingBlock Dimensions, c layoutContext) {
 	switch box.BoxType {
 	case InlineNode:
-		// TODO
-		panic("Inline Node Unimplemented")
+		// An inline box laid out on its own gets its own line boxes
+		anonymous := newLayoutBox(AnonymousBlock, nil)
+		anonymous.Children = append(anonymous.Children, box)
+		anonymous.layoutInline(containingBlock, c)
 	case BlockNode:
-		box.layoutBlock(containingBlock)
+		box.layoutBlock(containingBlock, c)
 	case AnonymousBlock:
-		// TODO
-		panic("Anonymous Block Unimplemented")
+		box.layoutInline(containingBlock, c)
 	}
 }
 
-func (box *LayoutBox) layoutBlock(containingBlock Dimensions) {
+func (box *LayoutBox) layoutBlock(containingBlock Dimensions, c layoutContext) {
 	// First go down the LayoutTree to compute the widths from parents' widths
 	// Then go up the tree to compute heights form children's heights
 
-	box.calculateWidth(containingBlock)
+	box.calculateWidth(containingBlock, c)
 
-	box.calculatePosition(containingBlock)
+	box.calculatePosition(containingBlock, c)
 
-	box.layoutBlockChildren()
+	box.layoutBlockChildren(c)
 
-	box.calculateHeight()
+	box.calculateHeight(containingBlock, c)
 }
 
-func (box *LayoutBox) calculateWidth(containingBlock Dimensions) {
+func (box *LayoutBox) calculateWidth(containingBlock Dimensions, c layoutContext) {
 	style := box.StyledNode
+	lengths := c.lengths(style, containingBlock.Content.Width)
 
 	// width has initial value auto
 	auto := css.Value{Keyword: "auto"}
@@ -180,42 +219,30 @@ func (box *LayoutBox) calculateWidth(containingBlock Dimensions) {
 	var marginLeft, marginRight, paddingLeft, paddingRight, borderLeft, borderRight css.Value
 
 	if marginLeft, ok = style.Value("margin-left"); !ok {
-		if marginLeft, ok = style.Value("margin"); !ok {
-			marginLeft = zero
-		}
+		marginLeft = zero
 	}
 	if marginRight, ok = style.Value("margin-right"); !ok {
-		if marginRight, ok = style.Value("margin"); !ok {
-			marginRight = zero
-		}
+		marginRight = zero
 	}
 
 	if borderLeft, ok = style.Value("border-left-width"); !ok {
-		if borderLeft, ok = style.Value("border-width"); !ok {
-			borderLeft = zero
-		}
+		borderLeft = zero
 	}
 	if borderRight, ok = style.Value("border-right-width"); !ok {
-		if borderRight, ok = style.Value("border-width"); !ok {
-			borderRight = zero
-		}
+		borderRight = zero
 	}
 
 	if paddingLeft, ok = style.Value("padding-left"); !ok {
-		if paddingLeft, ok = style.Value("padding"); !ok {
-			paddingLeft = zero
-		}
+		paddingLeft = zero
 	}
 	if paddingRight, ok = style.Value("padding-right"); !ok {
-		if paddingRight, ok = style.Value("padding"); !ok {
-			paddingRight = zero
-		}
+		paddingRight = zero
 	}
 
 	// Formula for block width: https://www.w3.org/TR/CSS2/visudet.html#blockwidth
 	// Auto must count as zero
-	total := marginLeft.ToPx() + marginRight.ToPx() + borderLeft.ToPx() + borderRight.ToPx() +
-		paddingLeft.ToPx() + paddingRight.ToPx() + width.ToPx()
+	total := marginLeft.ToPx(lengths) + marginRight.ToPx(lengths) + borderLeft.ToPx(lengths) + borderRight.ToPx(lengths) +
+		paddingLeft.ToPx(lengths) + paddingRight.ToPx(lengths) + width.ToPx(lengths)
 
 	// Checking if the box is too big
 	// If width is not auto and the total is wider than the container, treat auto margins as 0.
@@ -237,7 +264,7 @@ func (box *LayoutBox) calculateWidth(containingBlock Dimensions) {
 
 	if !widthAuto && !marginLeftAuto && !marginRightAuto {
 		// If the values are overconstrained, calculate margin_right
-		marginRight = css.Value{Length: css.Length{Quantity: marginRight.ToPx() + underflow, Unit: css.Px}}
+		marginRight = css.Value{Length: css.Length{Quantity: marginRight.ToPx(lengths) + underflow, Unit: css.Px}}
 	} else if !widthAuto && !marginLeftAuto && marginRightAuto {
 		// If exactly one size is auto, its used value follows from the equality
 		marginRight = css.Value{Length: css.Length{Quantity: underflow, Unit: css.Px}}
@@ -259,7 +286,7 @@ func (box *LayoutBox) calculateWidth(containingBlock Dimensions) {
 		} else {
 			// Width can't be negative. Adjust the right margin instead
 			width = css.Value{Length: css.Length{Quantity: 0.0, Unit: css.Px}}
-			marginRight = css.Value{Length: css.Length{Quantity: marginRight.ToPx() + underflow, Unit: css.Px}}
+			marginRight = css.Value{Length: css.Length{Quantity: marginRight.ToPx(lengths) + underflow, Unit: css.Px}}
 		}
 
 	} else if !widthAuto && marginLeftAuto && marginRightAuto {
@@ -268,20 +295,22 @@ func (box *LayoutBox) calculateWidth(containingBlock Dimensions) {
 		marginRight = css.Value{Length: css.Length{Quantity: underflow / 2.0, Unit: css.Px}}
 	}
 
-	box.Dimensions.Content.Width = width.ToPx()
+	box.Dimensions.Content.Width = width.ToPx(lengths)
 
-	box.Dimensions.padding.Left = paddingLeft.ToPx()
-	box.Dimensions.padding.Right = paddingRight.ToPx()
+	box.Dimensions.padding.Left = paddingLeft.ToPx(lengths)
+	box.Dimensions.padding.Right = paddingRight.ToPx(lengths)
 
-	box.Dimensions.Border.Left = borderLeft.ToPx()
-	box.Dimensions.Border.Right = borderRight.ToPx()
+	box.Dimensions.Border.Left = borderLeft.ToPx(lengths)
+	box.Dimensions.Border.Right = borderRight.ToPx(lengths)
 
-	box.Dimensions.margin.Left = marginLeft.ToPx()
-	box.Dimensions.margin.Right = marginRight.ToPx()
+	box.Dimensions.margin.Left = marginLeft.ToPx(lengths)
+	box.Dimensions.margin.Right = marginRight.ToPx(lengths)
 }
 
-func (box *LayoutBox) calculatePosition(containingBlock Dimensions) {
+func (box *LayoutBox) calculatePosition(containingBlock Dimensions, c layoutContext) {
 	style := box.StyledNode
+	// vertical margins and paddings are relative to the width of the containing block too
+	lengths := c.lengths(style, containingBlock.Content.Width)
 
 	// margin, border, and padding have initial value 0
 	zero := css.Value{Length: css.Length{Quantity: 0.0, Unit: css.Px}}
@@ -290,44 +319,32 @@ func (box *LayoutBox) calculatePosition(containingBlock Dimensions) {
 	var ok bool
 
 	if marginTop, ok = style.Value("margin-top"); !ok {
-		if marginTop, ok = style.Value("margin"); !ok {
-			marginTop = zero
-		}
+		marginTop = zero
 	}
 	if marginBottom, ok = style.Value("margin-bottom"); !ok {
-		if marginBottom, ok = style.Value("margin"); !ok {
-			marginBottom = zero
-		}
+		marginBottom = zero
 	}
 
 	if borderTop, ok = style.Value("border-top-width"); !ok {
-		if borderTop, ok = style.Value("border-width"); !ok {
-			borderTop = zero
-		}
+		borderTop = zero
 	}
 	if borderBottom, ok = style.Value("border-bottom-width"); !ok {
-		if borderBottom, ok = style.Value("border-width"); !ok {
-			borderBottom = zero
-		}
+		borderBottom = zero
 	}
 
 	if paddingTop, ok = style.Value("padding-top"); !ok {
-		if paddingTop, ok = style.Value("padding"); !ok {
-			paddingTop = zero
-		}
+		paddingTop = zero
 	}
 	if paddingBottom, ok = style.Value("padding-bottom"); !ok {
-		if paddingBottom, ok = style.Value("padding"); !ok {
-			paddingBottom = zero
-		}
+		paddingBottom = zero
 	}
 
-	box.Dimensions.margin.Top = marginTop.ToPx()
-	box.Dimensions.margin.Bottom = marginBottom.ToPx()
-	box.Dimensions.Border.Top = borderTop.ToPx()
-	box.Dimensions.Border.Bottom = borderBottom.ToPx()
-	box.Dimensions.padding.Top = paddingTop.ToPx()
-	box.Dimensions.padding.Bottom = paddingBottom.ToPx()
+	box.Dimensions.margin.Top = marginTop.ToPx(lengths)
+	box.Dimensions.margin.Bottom = marginBottom.ToPx(lengths)
+	box.Dimensions.Border.Top = borderTop.ToPx(lengths)
+	box.Dimensions.Border.Bottom = borderBottom.ToPx(lengths)
+	box.Dimensions.padding.Top = paddingTop.ToPx(lengths)
+	box.Dimensions.padding.Bottom = paddingBottom.ToPx(lengths)
 
 	box.Dimensions.Content.X = containingBlock.Content.X +
 		box.Dimensions.margin.Left + box.Dimensions.Border.Left + box.Dimensions.padding.Left
@@ -338,20 +355,23 @@ func (box *LayoutBox) calculatePosition(containingBlock Dimensions) {
 		box.Dimensions.margin.Top + box.Dimensions.Border.Top + box.Dimensions.padding.Top
 }
 
-func (box *LayoutBox) layoutBlockChildren() {
+func (box *LayoutBox) layoutBlockChildren(c layoutContext) {
 	for _, child := range box.Children {
-		child.Layout(box.Dimensions)
+		child.layout(box.Dimensions, c)
 		// Track the height so each child is laid out below the previous content
 		box.Dimensions.Content.Height = box.Dimensions.Content.Height + child.Dimensions.marginBox().Height
 	}
 }
 
-func (box *LayoutBox) calculateHeight() {
+func (box *LayoutBox) calculateHeight(containingBlock Dimensions, c layoutContext) {
 	// If the height is set to an explicit length, use that exact length
-	// Otherwise, just keep the value set by layoutBlockChildren()
+	// Otherwise, just keep the value set by layoutBlockChildren().
+	// The height of the containing block depends on its content, so percentages count as auto.
 	if height, ok := box.StyledNode.Value("height"); ok {
-		if height.Length.Unit == css.Px {
-			box.Dimensions.Content.Height = height.Length.Quantity
+		percent := height.Length.Unit == css.Percent ||
+			height.Math != nil && height.Math.HasUnit(func(u css.Unit) bool { return u == css.Percent })
+		if (height.Length.Unit != "" || height.Math != nil) && !percent {
+			box.Dimensions.Content.Height = height.ToPx(c.lengths(box.StyledNode, containingBlock.Content.Width))
 		}
 	}
 }
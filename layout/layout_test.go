@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/lysrt/bro/font"
 	"github.com/lysrt/bro/html"
 	"github.com/lysrt/bro/html/lexer"
 	"github.com/lysrt/bro/html/parser"
@@ -52,7 +53,7 @@ func TestGenerateLayoutTree(t *testing.T) {
 	}{
 		{
 			name: "Block Layout",
-			args: args{style.GenerateStyleTree(node, blockStyle)},
+			args: args{style.GenerateStyleTree(node, blockStyle, css.Device{})},
 			want: &LayoutBox{
 				BoxType: BlockNode,
 				Children: []*LayoutBox{
@@ -65,7 +66,7 @@ func TestGenerateLayoutTree(t *testing.T) {
 		},
 		{
 			name: "Inline Layout",
-			args: args{style.GenerateStyleTree(node, inlineStyle)},
+			args: args{style.GenerateStyleTree(node, inlineStyle, css.Device{})},
 			want: &LayoutBox{
 				BoxType: BlockNode,
 				Children: []*LayoutBox{
@@ -78,7 +79,7 @@ func TestGenerateLayoutTree(t *testing.T) {
 		},
 		{
 			name: "Mixed Layout",
-			args: args{style.GenerateStyleTree(node, mixedStyle)},
+			args: args{style.GenerateStyleTree(node, mixedStyle, css.Device{})},
 			want: &LayoutBox{
 				BoxType: BlockNode,
 				Children: []*LayoutBox{
@@ -109,3 +110,178 @@ func TestGenerateLayoutTree(t *testing.T) {
 		})
 	}
 }
+
+func TestLayoutInline(t *testing.T) {
+	l := lexer.New(`<p>aaaa bbbb <em>cccc dddd</em></p>`)
+	p := parser.New(l)
+	node := p.Parse()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+	// go through html -> body -> p
+	node = html.NodeLastElementChild(node)
+	node = html.NodeFirstElementChild(node)
+
+	// without the user-agent margins of p, the lines start at y=0
+	inlineStyle := css.NewParser(strings.NewReader(`em { display: inline; } p { margin-top: 0px; margin-bottom: 0px; }`)).ParseStylesheet()
+	root := GenerateLayoutTree(style.GenerateStyleTree(node, inlineStyle, css.Device{}))
+	// Each line fits two words
+	face := font.Default.Face("", "", "", defaultFontSize)
+	root.Layout(Dimensions{Content: Rect{Width: face.Width("aaaa bbbb") + 1}})
+
+	if len(root.Children) != 1 || root.Children[0].BoxType != AnonymousBlock {
+		t.Fatalf("expected a single anonymous block, got %v", root.Children)
+	}
+	lines := root.Children[0].Lines
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	tests := []struct {
+		line  int
+		texts []string
+	}{
+		{0, []string{"aaaa bbbb"}},
+		{1, []string{"", "cccc dddd"}},
+	}
+	for _, tt := range tests {
+		var texts []string
+		for _, f := range lines[tt.line].Fragments {
+			texts = append(texts, f.Text)
+		}
+		if strings.Join(texts, "|") != strings.Join(tt.texts, "|") {
+			t.Errorf("line %d: expected fragments %q got %q", tt.line, tt.texts, texts)
+		}
+	}
+
+	lineHeight := defaultFontSize * normalLineHeight
+	if lines[1].Rect.Y != lineHeight {
+		t.Errorf("second line: expected y=%v got %v", lineHeight, lines[1].Rect.Y)
+	}
+	if root.Dimensions.Content.Height != 2*lineHeight {
+		t.Errorf("expected height %v got %v", 2*lineHeight, root.Dimensions.Content.Height)
+	}
+
+	// A block nested in an inline box, like <a><div></div></a>, is laid out inline with its content
+	l = lexer.New(`<div><span><div>cccc</div></span> dddd</div>`)
+	p = parser.New(l)
+	node = html.NodeFirstElementChild(html.NodeLastElementChild(p.Parse()))
+	root = GenerateLayoutTree(style.GenerateStyleTree(node, inlineStyle, css.Device{}))
+	root.Layout(Dimensions{Content: Rect{Width: face.Width("aaaa bbbb") + 1}})
+
+	lines = root.Children[0].Lines
+	if len(lines) != 1 {
+		t.Fatalf("block in inline: expected 1 line, got %d", len(lines))
+	}
+	var texts []string
+	for _, f := range lines[0].Fragments {
+		texts = append(texts, f.Text)
+	}
+	if want := []string{"", "", "cccc", "dddd"}; strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("block in inline: expected fragments %q got %q", want, texts)
+	}
+}
+
+func TestLayout_shorthands(t *testing.T) {
+	l := lexer.New(`<section></section>`)
+	p := parser.New(l)
+	node := p.Parse()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+	// go through html -> body -> section
+	node = html.NodeLastElementChild(node)
+	node = html.NodeFirstElementChild(node)
+
+	stylesheet := css.NewParser(strings.NewReader(
+		`section { width: 100px; margin: 4px auto 0; border: 2px solid #000; padding: 1px 3px; }`,
+	)).ParseStylesheet()
+	root := GenerateLayoutTree(style.GenerateStyleTree(node, stylesheet, css.Device{}))
+	root.Layout(Dimensions{Content: Rect{Width: 300}})
+
+	d := root.Dimensions
+	want := Dimensions{
+		Content: Rect{X: 95 + 2 + 3, Y: 4 + 2 + 1, Width: 100, Height: 0},
+		padding: EdgeSizes{Left: 3, Right: 3, Top: 1, Bottom: 1},
+		Border:  EdgeSizes{Left: 2, Right: 2, Top: 2, Bottom: 2},
+		margin:  EdgeSizes{Left: 95, Right: 95, Top: 4, Bottom: 0},
+	}
+	if d != want {
+		t.Errorf("expected dimensions %+v got %+v", want, d)
+	}
+}
+
+func TestLayout_units(t *testing.T) {
+	l := lexer.New(`<section><div></div></section>`)
+	p := parser.New(l)
+	node := p.Parse()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+	// go through html -> body -> section
+	node = html.NodeLastElementChild(node)
+	node = html.NodeFirstElementChild(node)
+
+	stylesheet := css.NewParser(strings.NewReader(`
+		section { width: 50%; margin-left: 10vw; padding-top: 1in; height: 10vh; }
+		div { width: 50%; padding-left: 5%; margin-top: -.5em; }
+	`)).ParseStylesheet()
+	root := GenerateLayoutTree(style.GenerateStyleTree(node, stylesheet, css.Device{}))
+	root.Layout(Dimensions{Content: Rect{Width: 400, Height: 300}})
+
+	tests := []struct {
+		name string
+		got  Dimensions
+		want Dimensions
+	}{
+		{"section", root.Dimensions, Dimensions{
+			Content: Rect{X: 40, Y: 96, Width: 200, Height: 30},
+			padding: EdgeSizes{Top: 96},
+			margin:  EdgeSizes{Left: 40, Right: 160},
+		}},
+		// percentages refer to the width of the containing block
+		{"div", root.Children[0].Dimensions, Dimensions{
+			Content: Rect{X: 50, Y: 88, Width: 100},
+			padding: EdgeSizes{Left: 10},
+			margin:  EdgeSizes{Right: 90, Top: -8},
+		}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: expected dimensions %+v got %+v", tt.name, tt.want, tt.got)
+		}
+	}
+}
+
+func TestLayout_math(t *testing.T) {
+	l := lexer.New(`<section></section>`)
+	p := parser.New(l)
+	node := p.Parse()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+	// go through html -> body -> section
+	node = html.NodeLastElementChild(node)
+	node = html.NodeFirstElementChild(node)
+
+	stylesheet := css.NewParser(strings.NewReader(`
+		section {
+			width: calc(100% - 2 * 16px);
+			margin-left: max(10px, 5%);
+			padding-top: clamp(4px, 10vw, 20px);
+			height: calc(10vh + 1px);
+		}
+	`)).ParseStylesheet()
+	root := GenerateLayoutTree(style.GenerateStyleTree(node, stylesheet, css.Device{}))
+	root.Layout(Dimensions{Content: Rect{Width: 400, Height: 300}})
+
+	d := root.Dimensions
+	want := Dimensions{
+		Content: Rect{X: 20, Y: 20, Width: 368, Height: 31},
+		padding: EdgeSizes{Top: 20},
+		margin:  EdgeSizes{Left: 20, Right: 12},
+	}
+	if d != want {
+		t.Errorf("expected dimensions %+v got %+v", want, d)
+	}
+}
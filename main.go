@@ -7,8 +7,10 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/font"
 	"github.com/lysrt/bro/html"
 	"github.com/lysrt/bro/html/lexer"
 	"github.com/lysrt/bro/html/parser"
@@ -21,19 +23,38 @@ func main() {
 	var (
 		htmlInput string
 		cssInput  string
+		userCSS   string
 		pngOutput string
+		fontDir   string
+
+		width, height float64
+		colorScheme   string
 	)
 
 	flag.StringVar(&htmlInput, "html", "input.html", "-html input.html")
 	flag.StringVar(&cssInput, "css", "input.css", "-css input.css")
+	flag.StringVar(&userCSS, "user-css", "", "-user-css user.css")
 	flag.StringVar(&pngOutput, "o", "out.png", "-o out.png")
+	flag.StringVar(&fontDir, "fonts", "", "-fonts /usr/share/fonts/truetype")
+	flag.Float64Var(&width, "width", 300, "-width 300")
+	flag.Float64Var(&height, "height", 300, "-height 300")
+	flag.StringVar(&colorScheme, "color-scheme", "light", "-color-scheme dark")
 	flag.Parse()
 
+	if fontDir != "" {
+		if err := font.Default.LoadDir(fontDir); err != nil {
+			log.Printf("cannot load all the fonts: %q", err)
+		}
+	}
+
 	var (
 		domNodes   *html.Node
 		styleSheet *css.Stylesheet
 	)
 
+	// The @media rules are evaluated against the viewport the page is rendered in
+	device := css.Device{Type: "screen", Width: width, Height: height, ColorScheme: colorScheme}
+
 	//
 	// 1. Construct the DOM tree
 	//
@@ -51,12 +72,10 @@ func main() {
 	l := lexer.New(string(b))
 	p := parser.New(l)
 	domNodes = p.Parse()
-	if len(p.Errors()) > 0 {
-		log.Println("cannot parse HTML file")
-		for _, e := range p.Errors() {
-			log.Printf("%q (l: %d, c: %d)\n", e, e.Token.Line, e.Token.LinePosition)
-		}
-		log.Fatal()
+	// the parser recovers from malformed documents like browsers do,
+	// its errors are reported as warnings
+	for _, e := range p.Errors() {
+		log.Printf("HTML parsing error: %q (l: %d, c: %d)\n", e, e.Token.Line, e.Token.LinePosition)
 	}
 	htmlFile.Close()
 	// dom.Parcour(domNodes)
@@ -64,25 +83,32 @@ func main() {
 	//
 	// 2. Parse the CSS to a *Stylesheet
 	//
-	cssFile, err := os.Open(cssInput)
-	if err != nil {
-		log.Fatalf("cannot open stylesheet: %q", err)
+	styleSheet = loadStylesheet(cssInput)
+
+	// The stylesheets of the document come after the -css one
+	documentSheets, errs := style.DocumentStylesheets(domNodes, filepath.Dir(htmlInput))
+	for _, e := range errs {
+		log.Println(e)
 	}
+	styleSheet = css.Merge(append([]*css.Stylesheet{styleSheet}, documentSheets...)...)
 
-	parser := css.NewParser(cssFile)
-	cssFile.Close()
-	styleSheet = parser.ParseStylesheet()
-	if len(parser.Errors()) > 0 {
-		for _, e := range parser.Errors() {
-			log.Printf("parsing error: %q\n", e)
-		}
+	// The user stylesheet wins over the author ones for its !important declarations only
+	if userCSS != "" {
+		userSheet := loadStylesheet(userCSS)
+		userSheet.SetOrigin(css.User)
+		styleSheet = css.Merge(userSheet, styleSheet)
 	}
 	// fmt.Println(styleSheet)
 
+	// The fonts of the @font-face rules are used to lay out and paint text
+	for _, e := range style.LoadFontFaces(styleSheet, device, font.Default) {
+		log.Println(e)
+	}
+
 	//
 	// 3. Decorate the DOM to generate the Style Tree
 	//
-	styleTree := style.GenerateStyleTree(domNodes, styleSheet)
+	styleTree := style.GenerateStyleTree(domNodes, styleSheet, device)
 	// fmt.Println(styleTree)
 
 	//
@@ -94,7 +120,7 @@ func main() {
 	//
 	// 4.2 Parcour the layout tree to compute boxes dimensions
 	//
-	viewport := layout.Dimensions{Content: layout.Rect{Width: 300}}
+	viewport := layout.Dimensions{Content: layout.Rect{Width: width, Height: height}}
 	layoutTree.Layout(viewport)
 	// fmt.Println(layoutTree)
 
@@ -110,6 +136,17 @@ func main() {
 	writeOutput(pngOutput, pixels)
 }
 
+func loadStylesheet(fileName string) *css.Stylesheet {
+	styleSheet, errs := style.LoadStylesheet(fileName)
+	for _, e := range errs {
+		log.Println(e)
+	}
+	if styleSheet == nil {
+		log.Fatalf("cannot load stylesheet %q", fileName)
+	}
+	return styleSheet
+}
+
 func writeOutput(outputFileName string, pixels image.Image) {
 	f, err := os.Create(outputFileName)
 	if err != nil {
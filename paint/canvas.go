@@ -6,6 +6,7 @@ import (
 	"github.com/fogleman/gg"
 
 	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/font"
 )
 
 type Canvas struct {
@@ -42,3 +43,13 @@ func (c *Canvas) Rect(x, y, width, height int) {
 	c.context.DrawRectangle(float64(x), float64(y), float64(width), float64(height))
 	c.context.Fill()
 }
+
+// SetFont selects the font used by Text
+func (c *Canvas) SetFont(face *font.Face) {
+	c.context.SetFontFace(face.Face())
+}
+
+// Text draws a string with its baseline starting at x, y
+func (c *Canvas) Text(s string, x, y float64) {
+	c.context.DrawString(s, x, y)
+}
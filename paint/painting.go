@@ -4,6 +4,8 @@ import (
 	"image"
 
 	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/font"
+	"github.com/lysrt/bro/html"
 	"github.com/lysrt/bro/layout"
 )
 
@@ -27,6 +29,20 @@ func (c *SolidColor) paint(img *Canvas) {
 	img.Rect(x0, y0, width, height)
 }
 
+// Text draws a run of text, the baseline starting at x, y
+type Text struct {
+	text  string
+	color css.Color
+	face  *font.Face
+	x, y  float64
+}
+
+func (t *Text) paint(img *Canvas) {
+	img.SetColor(t.color)
+	img.SetFont(t.face)
+	img.Text(t.text, t.x, t.y)
+}
+
 func Paint(layoutRoot *layout.LayoutBox) (image.Image, error) {
 	displayList := buildDisplayList(layoutRoot)
 
@@ -50,7 +66,7 @@ func buildDisplayList(layoutRoot *layout.LayoutBox) DisplayList {
 func renderLayoutBox(list *DisplayList, layoutBox *layout.LayoutBox) {
 	renderBackground(list, layoutBox)
 	renderBorders(list, layoutBox)
-	// TODO render text
+	renderText(list, layoutBox)
 
 	for _, child := range layoutBox.Children {
 		renderLayoutBox(list, child)
@@ -64,56 +80,97 @@ func renderBackground(list *DisplayList, layoutBox *layout.LayoutBox) {
 }
 
 func renderBorders(list *DisplayList, layoutBox *layout.LayoutBox) {
-	var color css.Color
-	var ok bool
-
-	if color, ok = getColor(layoutBox, "border-color"); !ok {
-		// TODO is it OK to return if no border-color is specified?
-		return
-	}
-
 	d := layoutBox.Dimensions
 	borderBox := d.BorderBox()
 
 	// Left border
-	*list = append(*list, &SolidColor{color: color,
-		rect: layout.Rect{
-			X:      borderBox.X,
-			Y:      borderBox.Y,
-			Width:  d.Border.Left,
-			Height: borderBox.Height,
-		},
-	})
+	if color, ok := borderColor(layoutBox, "left"); ok {
+		*list = append(*list, &SolidColor{color: color,
+			rect: layout.Rect{
+				X:      borderBox.X,
+				Y:      borderBox.Y,
+				Width:  d.Border.Left,
+				Height: borderBox.Height,
+			},
+		})
+	}
 
 	// Right border
-	*list = append(*list, &SolidColor{color: color,
-		rect: layout.Rect{
-			X:      borderBox.X + borderBox.Width - d.Border.Right,
-			Y:      borderBox.Y,
-			Width:  d.Border.Right,
-			Height: borderBox.Height,
-		},
-	})
+	if color, ok := borderColor(layoutBox, "right"); ok {
+		*list = append(*list, &SolidColor{color: color,
+			rect: layout.Rect{
+				X:      borderBox.X + borderBox.Width - d.Border.Right,
+				Y:      borderBox.Y,
+				Width:  d.Border.Right,
+				Height: borderBox.Height,
+			},
+		})
+	}
 
 	// Top border
-	*list = append(*list, &SolidColor{color: color,
-		rect: layout.Rect{
-			X:      borderBox.X,
-			Y:      borderBox.Y,
-			Width:  borderBox.Width,
-			Height: d.Border.Top,
-		},
-	})
+	if color, ok := borderColor(layoutBox, "top"); ok {
+		*list = append(*list, &SolidColor{color: color,
+			rect: layout.Rect{
+				X:      borderBox.X,
+				Y:      borderBox.Y,
+				Width:  borderBox.Width,
+				Height: d.Border.Top,
+			},
+		})
+	}
 
 	// Bottom border
-	*list = append(*list, &SolidColor{color: color,
-		rect: layout.Rect{
-			X:      borderBox.X,
-			Y:      borderBox.Y + borderBox.Height - d.Border.Bottom,
-			Width:  borderBox.Width,
-			Height: d.Border.Bottom,
-		},
-	})
+	if color, ok := borderColor(layoutBox, "bottom"); ok {
+		*list = append(*list, &SolidColor{color: color,
+			rect: layout.Rect{
+				X:      borderBox.X,
+				Y:      borderBox.Y + borderBox.Height - d.Border.Bottom,
+				Width:  borderBox.Width,
+				Height: d.Border.Bottom,
+			},
+		})
+	}
+}
+
+// borderColor returns the color of a side of the border.
+// A border with a style but no color, like `border: 1px solid`, takes the color of the text.
+func borderColor(layoutBox *layout.LayoutBox, side string) (css.Color, bool) {
+	if color, ok := getColor(layoutBox, "border-"+side+"-color"); ok {
+		return color, true
+	}
+	if layoutBox.StyledNode == nil {
+		return css.Color{}, false
+	}
+	style, ok := layoutBox.StyledNode.Value("border-" + side + "-style")
+	if !ok || style.Keyword == "none" || style.Keyword == "hidden" {
+		return css.Color{}, false
+	}
+	if color, ok := getColor(layoutBox, "color"); ok {
+		return color, true
+	}
+	return css.Color{A: 255}, true
+}
+
+func renderText(list *DisplayList, layoutBox *layout.LayoutBox) {
+	if layoutBox.StyledNode == nil || layoutBox.StyledNode.Node.Type != html.NodeText {
+		return
+	}
+
+	// Text is black unless a color is specified
+	color, ok := getColor(layoutBox, "color")
+	if !ok {
+		color = css.Color{A: 255}
+	}
+
+	for _, f := range layoutBox.Fragments {
+		*list = append(*list, &Text{
+			text:  f.Text,
+			color: color,
+			face:  f.Face,
+			x:     f.Rect.X,
+			y:     f.Baseline,
+		})
+	}
 }
 
 func getColor(layoutBox *layout.LayoutBox, name string) (color css.Color, ok bool) {
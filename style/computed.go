@@ -0,0 +1,271 @@
+package style
+
+import (
+	"strings"
+
+	"github.com/lysrt/bro/css"
+)
+
+// mediumFontSize is the font size in pixels of the medium keyword, used when none is specified.
+const mediumFontSize = 16.0
+
+// inheritedProperties are the properties an element takes from its parent when it does not set them.
+// See https://www.w3.org/TR/CSS22/propidx.html
+var inheritedProperties = map[string]bool{
+	"color":           true,
+	"cursor":          true,
+	"direction":       true,
+	"font-family":     true,
+	"font-size":       true,
+	"font-style":      true,
+	"font-variant":    true,
+	"font-weight":     true,
+	"letter-spacing":  true,
+	"line-height":     true,
+	"list-style-type": true,
+	"quotes":          true,
+	"text-align":      true,
+	"text-indent":     true,
+	"text-transform":  true,
+	"visibility":      true,
+	"white-space":     true,
+	"word-spacing":    true,
+}
+
+// initialValues are the initial values of the properties whose absence does not already mean it.
+// Layout and paint use the initial value of a missing property, like 0 for margin-left or black for color.
+var initialValues = map[string]css.Value{
+	"display": {Keyword: "inline"},
+}
+
+// borderWidthKeywords are the widths in pixels of the border width keywords.
+var borderWidthKeywords = map[string]float64{
+	"thin":   1,
+	"medium": 3,
+	"thick":  5,
+}
+
+// fontSizeKeywords are the sizes in pixels of the absolute font-size keywords.
+var fontSizeKeywords = map[string]float64{
+	"xx-small":  9,
+	"x-small":   10,
+	"small":     13,
+	"medium":    mediumFontSize,
+	"large":     18,
+	"x-large":   24,
+	"xx-large":  32,
+	"xxx-large": 48,
+}
+
+// computeValues returns the computed values of a node from its specified values
+// and the computed values of its parent, which is nil for the root.
+// The var() of the values are substituted with the custom properties of the node,
+// inherited properties the node does not set take the value of the parent,
+// the inherit, initial and unset keywords are applied,
+// font-relative and absolute lengths and width keywords are resolved to pixels, and color keywords to colors.
+// rem lengths are relative to the root font size of context. Percentages and viewport lengths are left to layout,
+// except in font-size, which resolves viewport lengths against the viewport of context.
+// See https://www.w3.org/TR/css-cascade-4/#computed
+func computeValues(specified, parent PropertyMap, context css.LengthContext) PropertyMap {
+	computed := make(PropertyMap)
+	for name := range inheritedProperties {
+		if value, ok := parent[name]; ok {
+			computed[name] = value
+		}
+	}
+
+	// the custom properties are computed first, as the values using var() refer to them
+	custom := computeCustomProperties(specified, parent)
+	for name, value := range custom {
+		computed[name] = value
+	}
+
+	for name, value := range specified {
+		if css.IsCustomProperty(name) {
+			continue
+		}
+		if value.Raw != nil {
+			value = substituteVariables(name, value, custom)
+		}
+
+		switch strings.ToLower(value.Keyword) {
+		case "inherit":
+			inherit(computed, parent, name)
+		case "initial":
+			initial(computed, name)
+		case "unset":
+			if inheritedProperties[name] {
+				inherit(computed, parent, name)
+			} else {
+				initial(computed, name)
+			}
+		default:
+			computed[name] = value
+		}
+	}
+
+	parentFontSize := mediumFontSize
+	if value, ok := parent["font-size"]; ok && value.Length.Unit == css.Px {
+		parentFontSize = value.Length.Quantity
+	}
+	fontSize := parentFontSize
+	if value, ok := computed["font-size"]; ok {
+		fontSize = computeFontSize(value, parentFontSize, context)
+		computed["font-size"] = css.Value{Length: css.Length{Quantity: fontSize, Unit: css.Px}}
+	}
+
+	if value, ok := computed["font-weight"]; ok {
+		computed["font-weight"] = computeFontWeight(value, parent["font-weight"])
+	}
+
+	// currentColor is the color of the text, and its inherited value for the color property itself
+	if value, ok := computed["color"]; ok {
+		computed["color"] = computeColor(value, inheritedColor(parent))
+	}
+	currentColor := inheritedColor(computed)
+	lengths := context
+	lengths.FontSize = fontSize
+
+	for name, value := range computed {
+		switch {
+		case name == "font-size" || name == "color":
+		case strings.HasSuffix(name, "-color"):
+			computed[name] = computeColor(value, currentColor)
+		case value.Math != nil:
+			computed[name] = computeMath(value, lengths)
+		case value.Length.Unit != css.Px && (value.Length.Unit.FontRelative() || value.Length.Unit.Absolute()):
+			computed[name] = css.Value{Length: css.Length{Quantity: value.ToPx(lengths), Unit: css.Px}}
+		case name == "line-height" && value.Length.Unit == css.Percent:
+			computed[name] = css.Value{Length: css.Length{Quantity: value.Length.Quantity * fontSize / 100, Unit: css.Px}}
+		case strings.HasPrefix(name, "border-") && strings.HasSuffix(name, "-width"):
+			if width, ok := borderWidthKeywords[strings.ToLower(value.Keyword)]; ok {
+				computed[name] = css.Value{Length: css.Length{Quantity: width, Unit: css.Px}}
+			}
+		}
+	}
+
+	return computed
+}
+
+// inherit gives a property the computed value of the parent, or its initial value at the root.
+func inherit(computed, parent PropertyMap, name string) {
+	if value, ok := parent[name]; ok {
+		computed[name] = value
+		return
+	}
+	initial(computed, name)
+}
+
+func initial(computed PropertyMap, name string) {
+	if value, ok := initialValues[name]; ok {
+		computed[name] = value
+		return
+	}
+	delete(computed, name)
+}
+
+// computeColor returns the color of a named color keyword, or the current color for currentColor.
+func computeColor(value, currentColor css.Value) css.Value {
+	if strings.EqualFold(value.Keyword, "currentcolor") {
+		return currentColor
+	}
+	if color, ok := css.NamedColor(value.Keyword); ok {
+		return css.Value{Color: color}
+	}
+	return value
+}
+
+// inheritedColor returns the computed color of a node, black if it has none.
+func inheritedColor(computed PropertyMap) css.Value {
+	if value, ok := computed["color"]; ok {
+		return value
+	}
+	black, _ := css.NamedColor("black")
+	return css.Value{Color: black}
+}
+
+// computeFontSize returns the size in pixels of a font-size value.
+// Keywords, em and percentages are relative to the font size of the parent,
+// rem and viewport lengths to the root font size and the viewport of context.
+func computeFontSize(value css.Value, parentFontSize float64, context css.LengthContext) float64 {
+	switch keyword := strings.ToLower(value.Keyword); {
+	case keyword == "larger":
+		return parentFontSize * 1.2
+	case keyword == "smaller":
+		return parentFontSize / 1.2
+	case keyword != "":
+		if size, ok := fontSizeKeywords[keyword]; ok {
+			return size
+		}
+		return parentFontSize
+	}
+
+	// percentages are relative to the font size of the parent
+	context.FontSize = parentFontSize
+	context.PercentBase = parentFontSize
+	switch unit := value.Length.Unit; {
+	case value.Math != nil:
+		return value.Math.Eval(context)
+	case unit == css.Percent || unit.FontRelative() || unit.Absolute() || unit.ViewportRelative():
+		return value.ToPx(context)
+	}
+	return parentFontSize
+}
+
+// computeMath evaluates a math expression to a number or a length in pixels,
+// unless it has percentages or viewport lengths, which are left to layout.
+func computeMath(value css.Value, lengths css.LengthContext) css.Value {
+	if value.Math.HasUnit(func(u css.Unit) bool { return u == css.Percent || u.ViewportRelative() }) {
+		return value
+	}
+	if value.Math.IsNumber() {
+		return css.Value{Length: css.Length{Quantity: value.Math.Eval(lengths)}}
+	}
+	return css.Value{Length: css.Length{Quantity: value.Math.Eval(lengths), Unit: css.Px}}
+}
+
+// computeFontWeight resolves the bolder and lighter keywords against the weight of the parent.
+// See https://www.w3.org/TR/css-fonts-4/#relative-weights
+func computeFontWeight(value, parent css.Value) css.Value {
+	keyword := strings.ToLower(value.Keyword)
+	if keyword != "bolder" && keyword != "lighter" {
+		return value
+	}
+
+	inherited := fontWeight(parent)
+	weight := inherited
+	if keyword == "bolder" {
+		switch {
+		case inherited < 350:
+			weight = 400
+		case inherited < 550:
+			weight = 700
+		case inherited < 900:
+			weight = 900
+		}
+	} else {
+		switch {
+		case inherited >= 750:
+			weight = 700
+		case inherited >= 550:
+			weight = 400
+		case inherited >= 100:
+			weight = 100
+		}
+	}
+	return css.Value{Length: css.Length{Quantity: weight}}
+}
+
+// fontWeight returns the numeric weight of a computed font-weight value.
+func fontWeight(value css.Value) float64 {
+	switch strings.ToLower(value.Keyword) {
+	case "bold":
+		return 700
+	case "normal", "":
+		if value.Length.Quantity > 0 {
+			return value.Length.Quantity
+		}
+		return 400
+	}
+	return 400
+}
@@ -0,0 +1,266 @@
+package style
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lysrt/bro/css"
+)
+
+func px(quantity float64) css.Value {
+	return css.Value{Length: css.Length{Quantity: quantity, Unit: css.Px}}
+}
+
+var red = css.Value{Color: css.Color{Name: "red", A: 255, R: 255}}
+
+func Test_computeValues(t *testing.T) {
+	parent := PropertyMap{
+		"color":       red,
+		"font-size":   px(20),
+		"font-weight": {Keyword: "bold"},
+		"width":       px(100),
+	}
+	tests := []struct {
+		name      string
+		specified PropertyMap
+		want      PropertyMap
+	}{
+		{
+			name:      "inherited properties only",
+			specified: PropertyMap{},
+			want:      PropertyMap{"color": red, "font-size": px(20), "font-weight": {Keyword: "bold"}},
+		},
+		{
+			name:      "inherit",
+			specified: PropertyMap{"width": {Keyword: "inherit"}},
+			want:      PropertyMap{"color": red, "font-size": px(20), "font-weight": {Keyword: "bold"}, "width": px(100)},
+		},
+		{
+			name:      "initial",
+			specified: PropertyMap{"color": {Keyword: "initial"}, "display": {Keyword: "INITIAL"}},
+			want:      PropertyMap{"font-size": px(20), "font-weight": {Keyword: "bold"}, "display": {Keyword: "inline"}},
+		},
+		{
+			name:      "unset",
+			specified: PropertyMap{"color": {Keyword: "unset"}, "width": {Keyword: "unset"}},
+			want:      PropertyMap{"color": red, "font-size": px(20), "font-weight": {Keyword: "bold"}},
+		},
+		{
+			name: "em",
+			specified: PropertyMap{
+				"font-size":   {Length: css.Length{Quantity: 1.5, Unit: css.Em}},
+				"margin-left": {Length: css.Length{Quantity: 2, Unit: css.Em}},
+				"line-height": {Length: css.Length{Quantity: 1.5}},
+			},
+			want: PropertyMap{
+				"color":       red,
+				"font-size":   px(30),
+				"font-weight": {Keyword: "bold"},
+				"margin-left": px(60),
+				"line-height": {Length: css.Length{Quantity: 1.5}},
+			},
+		},
+		{
+			name: "keywords",
+			specified: PropertyMap{
+				"font-size":   {Keyword: "x-large"},
+				"font-weight": {Keyword: "lighter"},
+			},
+			want: PropertyMap{"color": red, "font-size": px(24), "font-weight": {Length: css.Length{Quantity: 400}}},
+		},
+		{
+			name:      "larger",
+			specified: PropertyMap{"font-size": {Keyword: "larger"}, "font-weight": {Keyword: "bolder"}},
+			want:      PropertyMap{"color": red, "font-size": px(24), "font-weight": {Length: css.Length{Quantity: 900}}},
+		},
+		{
+			name: "colors",
+			specified: PropertyMap{
+				"color":            {Keyword: "currentColor"},
+				"background-color": {Keyword: "Transparent"},
+				"border-top-color": {Keyword: "currentcolor"},
+			},
+			want: PropertyMap{
+				"color":            red,
+				"font-size":        px(20),
+				"font-weight":      {Keyword: "bold"},
+				"background-color": {Color: css.Transparent},
+				"border-top-color": red,
+			},
+		},
+		{
+			name: "units",
+			specified: PropertyMap{
+				"font-size":    {Length: css.Length{Quantity: 0.5, Unit: css.In}},
+				"margin-left":  {Length: css.Length{Quantity: 2, Unit: css.Rem}},
+				"margin-right": {Length: css.Length{Quantity: -1, Unit: css.Em}},
+				"padding-left": {Length: css.Length{Quantity: 12, Unit: css.Pt}},
+				"text-indent":  {Length: css.Length{Quantity: 2, Unit: css.Ch}},
+				"width":        {Length: css.Length{Quantity: 50, Unit: css.Percent}},
+				"height":       {Length: css.Length{Quantity: 10, Unit: css.Vh}},
+			},
+			want: PropertyMap{
+				"color":        red,
+				"font-size":    px(48),
+				"font-weight":  {Keyword: "bold"},
+				"margin-left":  px(32),
+				"margin-right": px(-48),
+				"padding-left": px(16),
+				"text-indent":  px(48),
+				"width":        {Length: css.Length{Quantity: 50, Unit: css.Percent}},
+				"height":       {Length: css.Length{Quantity: 10, Unit: css.Vh}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeValues(tt.specified, parent, css.LengthContext{RootFontSize: mediumFontSize}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("computeValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_computeValues_root(t *testing.T) {
+	specified := PropertyMap{
+		"color":     {Keyword: "inherit"},
+		"font-size": {Length: css.Length{Quantity: 2, Unit: css.Em}},
+		"width":     {Length: css.Length{Quantity: 1, Unit: css.Em}},
+	}
+	want := PropertyMap{"font-size": px(32), "width": px(32)}
+	if got := computeValues(specified, nil, css.LengthContext{RootFontSize: mediumFontSize}); !reflect.DeepEqual(got, want) {
+		t.Errorf("computeValues() = %v, want %v", got, want)
+	}
+}
+
+func Test_cascade_revert(t *testing.T) {
+	node := htmlParseSnippet(t, `<p>text</p>`)
+	parse := func(stylesheet string, origin css.Origin) *css.Stylesheet {
+		s := css.NewParser(strings.NewReader(stylesheet)).ParseStylesheet()
+		s.SetOrigin(origin)
+		return s
+	}
+	style := css.Merge(
+		parse(`p { display: block; color: green; }`, css.UserAgent),
+		parse(`p { color: blue; width: 5px; height: revert; }`, css.User),
+		parse(`p { display: revert; color: revert; width: revert; height: revert; }`, css.Author),
+	)
+
+	want := PropertyMap{
+		"display": {Keyword: "block"},
+		"color":   {Keyword: "blue"},
+		"width":   px(5),
+		"height":  {Keyword: "unset"},
+	}
+	if got := specifiedValues(node, style); !reflect.DeepEqual(got, want) {
+		t.Errorf("specifiedValues() = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateStyleTree_inheritance(t *testing.T) {
+	node := htmlParseSnippet(t, `<div><p>a <span>b</span></p></div>`)
+	p := css.NewParser(strings.NewReader(`
+		div { color: red; font-size: 20px; width: 100px; }
+		p { font-size: 1.5em; margin-top: 1em; }
+		span { font-size: 0.5em; color: inherit; }
+	`))
+	tree := GenerateStyleTree(node, p.ParseStylesheet(), css.Device{})
+
+	paragraph := tree.Children[0]
+	text := paragraph.Children[0]
+	span := paragraph.Children[1]
+	spanText := span.Children[0]
+
+	tests := []struct {
+		name string
+		node *StyledNode
+		want PropertyMap
+	}{
+		{"p", paragraph, PropertyMap{
+			"color":         red,
+			"font-size":     px(30),
+			"margin-top":    px(30),
+			"margin-bottom": px(30), // 1em in the user-agent stylesheet
+			"display":       {Keyword: "block"},
+		}},
+		{"text", text, PropertyMap{"color": red, "font-size": px(30)}},
+		{"span text", spanText, PropertyMap{"color": red, "font-size": px(15)}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.node.ComputedValues, tt.want) {
+			t.Errorf("%s: computed values = %v, want %v", tt.name, tt.node.ComputedValues, tt.want)
+		}
+	}
+	if len(text.SpecifiedValues) != 0 {
+		t.Errorf("text nodes have no specified values, got %v", text.SpecifiedValues)
+	}
+}
+
+func TestGenerateStyleTree_rem(t *testing.T) {
+	node := htmlParseSnippet(t, `<div><p></p></div>`)
+	p := css.NewParser(strings.NewReader(`
+		div { font-size: 1.25rem; }
+		p { font-size: 2rem; margin-left: 1rem; margin-top: 0; margin-bottom: 0; }
+	`))
+	tree := GenerateStyleTree(node, p.ParseStylesheet(), css.Device{})
+
+	// the rem of the root element is the initial font size, the others use the font size of the root
+	if got, want := tree.ComputedValues["font-size"], px(20); got != want {
+		t.Errorf("root font-size = %v, want %v", got, want)
+	}
+	paragraph := tree.Children[0]
+	want := PropertyMap{
+		"font-size":     px(40),
+		"margin-left":   px(20),
+		"margin-top":    {},
+		"margin-bottom": {},
+		"display":       {Keyword: "block"},
+	}
+	if !reflect.DeepEqual(paragraph.ComputedValues, want) {
+		t.Errorf("p: computed values = %v, want %v", paragraph.ComputedValues, want)
+	}
+
+	// a root element without font-size keeps the initial font size for rem
+	p = css.NewParser(strings.NewReader(`p { margin-left: 2rem; }`))
+	tree = GenerateStyleTree(node, p.ParseStylesheet(), css.Device{})
+	if got, want := tree.Children[0].ComputedValues["margin-left"], px(32); got != want {
+		t.Errorf("p margin-left = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateStyleTree_math(t *testing.T) {
+	node := htmlParseSnippet(t, `<div></div>`)
+	p := css.NewParser(strings.NewReader(`
+		div {
+			font-size: calc(1em + 4px);
+			width: calc(1em + 2px);
+			height: max(1in, 50px);
+			line-height: calc(3 / 2);
+			margin-left: calc(50% - 1em);
+		}
+	`))
+	tree := GenerateStyleTree(node, p.ParseStylesheet(), css.Device{})
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+
+	tests := []struct {
+		name string
+		want css.Value
+	}{
+		{"font-size", px(20)},
+		{"width", px(22)},
+		{"height", px(96)},
+		{"line-height", css.Value{Length: css.Length{Quantity: 1.5}}},
+	}
+	for _, tt := range tests {
+		if got := tree.ComputedValues[tt.name]; got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	// percentages are resolved by layout
+	if got := tree.ComputedValues["margin-left"]; got.Math == nil {
+		t.Errorf("margin-left = %v, want a math expression", got)
+	}
+}
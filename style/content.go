@@ -0,0 +1,277 @@
+package style
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/html"
+)
+
+// generatePseudoElement returns the node generated by the ::before or ::after pseudo-element
+// of an element, or nil if its content property generates no box.
+// The pseudo-element inherits from the computed values of its element.
+func (b *treeBuilder) generatePseudoElement(element *html.Node, parent PropertyMap, name string) *StyledNode {
+	rules := matchingRules(element, b.stylesheet, name)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	specified := cascade(rules)
+	computed := computeValues(specified, parent, b.lengths)
+	content, ok := computed["content"]
+	if !ok || isNoContent(content) || isDisplayNone(computed) {
+		return nil
+	}
+
+	// the counters are updated before the content uses them: li::before { counter-increment: item; content: counter(item) }
+	b.counters.update(computed)
+	text, ok := b.generatedContent(element, content)
+	if !ok {
+		return nil
+	}
+
+	styled := &StyledNode{
+		Node:            &html.Node{Type: html.NodeElement, Tag: "::" + name},
+		SpecifiedValues: specified,
+		ComputedValues:  computed,
+		PseudoElement:   name,
+	}
+	if text != "" {
+		styled.Children = []*StyledNode{{
+			Node:            &html.Node{Type: html.NodeText, TextContent: text},
+			SpecifiedValues: make(PropertyMap),
+			ComputedValues:  computeValues(nil, computed, b.lengths),
+		}}
+	}
+	return styled
+}
+
+func isNoContent(v css.Value) bool {
+	return v.Keyword == "none" || v.Keyword == "normal"
+}
+
+// generatedContent returns the text generated by a content property value,
+// made of strings, attr(), counter() and counters().
+// It returns false if the value is invalid.
+func (b *treeBuilder) generatedContent(element *html.Node, content css.Value) (string, bool) {
+	var text strings.Builder
+	for _, c := range components(content) {
+		switch {
+		case c.Function != nil:
+			s, ok := b.contentFunction(element, c.Function)
+			if !ok {
+				return "", false
+			}
+			text.WriteString(s)
+		case c.Keyword == "open-quote":
+			text.WriteString("“")
+		case c.Keyword == "close-quote":
+			text.WriteString("”")
+		case c.Keyword == "no-open-quote", c.Keyword == "no-close-quote":
+		case c.Keyword != "" || c.List != nil:
+			return "", false
+		default:
+			text.WriteString(c.Text)
+		}
+	}
+	return text.String(), true
+}
+
+// contentFunction evaluates attr(name), counter(name, style) and counters(name, separator, style).
+func (b *treeBuilder) contentFunction(element *html.Node, f *css.Function) (string, bool) {
+	if len(f.Args) == 0 || f.Args[0].Keyword == "" {
+		return "", false
+	}
+	name := f.Args[0].Keyword
+
+	switch f.Name {
+	case "attr":
+		value, _ := attribute(element, name)
+		return value, true
+
+	case "counter":
+		listStyle := "decimal"
+		if len(f.Args) > 1 {
+			listStyle = f.Args[1].Keyword
+		}
+		values := b.counters.values[name]
+		if len(values) == 0 {
+			return formatCounter(0, listStyle), true
+		}
+		return formatCounter(values[len(values)-1], listStyle), true
+
+	case "counters":
+		if len(f.Args) < 2 {
+			return "", false
+		}
+		separator := f.Args[1].Text
+		listStyle := "decimal"
+		if len(f.Args) > 2 {
+			listStyle = f.Args[2].Keyword
+		}
+		values := b.counters.values[name]
+		if len(values) == 0 {
+			return formatCounter(0, listStyle), true
+		}
+		formatted := make([]string, len(values))
+		for i, v := range values {
+			formatted[i] = formatCounter(v, listStyle)
+		}
+		return strings.Join(formatted, separator), true
+	}
+	return "", false
+}
+
+// formatCounter returns the representation of a counter value in a list style,
+// like "iv" for 4 in lower-roman.
+func formatCounter(value int, listStyle string) string {
+	switch listStyle {
+	case "none":
+		return ""
+	case "disc":
+		return "•"
+	case "circle":
+		return "◦"
+	case "square":
+		return "▪"
+	case "lower-alpha", "lower-latin":
+		return alphabetic(value, 'a')
+	case "upper-alpha", "upper-latin":
+		return alphabetic(value, 'A')
+	case "lower-roman":
+		return strings.ToLower(roman(value))
+	case "upper-roman":
+		return roman(value)
+	}
+	return strconv.Itoa(value)
+}
+
+// alphabetic returns a, b, ..., z, aa, ab... for 1, 2, ..., 26, 27, 28...
+// Values below 1 use decimal.
+func alphabetic(value int, first byte) string {
+	if value < 1 {
+		return strconv.Itoa(value)
+	}
+	var s []byte
+	for value > 0 {
+		value--
+		s = append([]byte{first + byte(value%26)}, s...)
+		value /= 26
+	}
+	return string(s)
+}
+
+// roman returns the roman numeral of values from 1 to 3999, other values use decimal.
+func roman(value int) string {
+	if value < 1 || value > 3999 {
+		return strconv.Itoa(value)
+	}
+	numerals := []struct {
+		value int
+		s     string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"},
+		{100, "C"}, {90, "XC"}, {50, "L"}, {40, "XL"},
+		{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+	var s strings.Builder
+	for _, n := range numerals {
+		for value >= n.value {
+			s.WriteString(n.s)
+			value -= n.value
+		}
+	}
+	return s.String()
+}
+
+// counters holds the CSS counters in scope while the style tree is generated.
+// A counter created by counter-reset on an element is visible to the element,
+// its descendants and its following siblings.
+// See https://www.w3.org/TR/css-lists-3/#auto-numbering
+type counters struct {
+	values map[string][]int // nested instances of each counter, innermost last
+	scopes [][]string       // names of the counters created by the children of each open element
+}
+
+func newCounters() *counters {
+	return &counters{
+		values: make(map[string][]int),
+		scopes: [][]string{nil},
+	}
+}
+
+// enter opens the scope of the children of an element.
+func (c *counters) enter() {
+	c.scopes = append(c.scopes, nil)
+}
+
+// leave closes the scope of the children of an element, removing the counters they created.
+func (c *counters) leave() {
+	last := len(c.scopes) - 1
+	for _, name := range c.scopes[last] {
+		values := c.values[name]
+		c.values[name] = values[:len(values)-1]
+	}
+	c.scopes = c.scopes[:last]
+}
+
+// update applies the counter-reset and counter-increment properties of a node.
+func (c *counters) update(properties PropertyMap) {
+	if value, ok := properties["counter-reset"]; ok {
+		for _, change := range counterChanges(value, 0) {
+			c.reset(change.name, change.value)
+		}
+	}
+	if value, ok := properties["counter-increment"]; ok {
+		for _, change := range counterChanges(value, 1) {
+			c.increment(change.name, change.value)
+		}
+	}
+}
+
+// reset creates a new instance of a counter.
+// A counter created by a previous sibling is replaced instead.
+func (c *counters) reset(name string, value int) {
+	scope := len(c.scopes) - 1
+	for _, n := range c.scopes[scope] {
+		if n == name {
+			values := c.values[name]
+			values[len(values)-1] = value
+			return
+		}
+	}
+	c.values[name] = append(c.values[name], value)
+	c.scopes[scope] = append(c.scopes[scope], name)
+}
+
+// increment adds to the innermost instance of a counter, creating it if there is none.
+func (c *counters) increment(name string, value int) {
+	if len(c.values[name]) == 0 {
+		c.reset(name, 0)
+	}
+	values := c.values[name]
+	values[len(values)-1] += value
+}
+
+type counterChange struct {
+	name  string
+	value int
+}
+
+// counterChanges returns the counters of a counter-reset or counter-increment value,
+// like `section 2 figure`. Counters without a number take the default value.
+func counterChanges(v css.Value, defaultValue int) []counterChange {
+	var changes []counterChange
+	for _, c := range components(v) {
+		switch {
+		case c.Keyword == "none":
+			return nil
+		case c.Keyword != "":
+			changes = append(changes, counterChange{name: c.Keyword, value: defaultValue})
+		case len(changes) > 0 && c.Length.Unit == "":
+			changes[len(changes)-1].value = int(c.Length.Quantity)
+		}
+	}
+	return changes
+}
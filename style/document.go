@@ -0,0 +1,194 @@
+package style
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/html"
+)
+
+// DocumentStylesheets returns the stylesheets of a document, in document order:
+// the content of its <style> elements and the files of its <link rel="stylesheet"> elements.
+// Linked files are resolved relative to dir, the directory of the HTML file.
+// Stylesheets which cannot be loaded are skipped, and reported with the parsing errors.
+// The stylesheets they import are loaded too, see LoadStylesheet.
+func DocumentStylesheets(root *html.Node, dir string) ([]*css.Stylesheet, []error) {
+	var sheets []*css.Stylesheet
+	l := newStylesheetLoader()
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type != html.NodeElement {
+			return
+		}
+
+		switch {
+		case n.Tag == "style":
+			var text string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.NodeText {
+					text += c.TextContent
+				}
+			}
+			sheets = append(sheets, l.parse("<style>", strings.NewReader(text), dir))
+
+		case n.Tag == "link" && isStylesheetLink(n):
+			if sheet := l.load(dir, n.Attributes["href"]); sheet != nil {
+				sheets = append(sheets, sheet)
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	return sheets, l.errs
+}
+
+// LoadStylesheet parses a local stylesheet file, with the stylesheets it imports.
+// It returns nil if the file cannot be opened. The errors of the imports are returned with the parsing ones.
+func LoadStylesheet(path string) (*css.Stylesheet, []error) {
+	l := newStylesheetLoader()
+	sheet := l.load(filepath.Dir(path), filepath.Base(path))
+	return sheet, l.errs
+}
+
+// isStylesheetLink reports whether a <link> element refers to a stylesheet applied by default.
+func isStylesheetLink(n *html.Node) bool {
+	rel := strings.Fields(strings.ToLower(n.Attributes["rel"]))
+	var stylesheet bool
+	for _, r := range rel {
+		switch r {
+		case "stylesheet":
+			stylesheet = true
+		case "alternate":
+			// alternate stylesheets are disabled until the user selects them
+			return false
+		}
+	}
+	return stylesheet && n.Attributes["href"] != ""
+}
+
+// stylesheetLoader parses stylesheets, and loads the local files they import.
+type stylesheetLoader struct {
+	// importing holds the files being loaded, to skip the cycles of imports
+	importing map[string]bool
+	errs      []error
+}
+
+func newStylesheetLoader() *stylesheetLoader {
+	return &stylesheetLoader{importing: make(map[string]bool)}
+}
+
+// load parses the local file referenced by href, relative to dir.
+// It returns nil if the file cannot be loaded, or is already being loaded by an import cycle.
+func (l *stylesheetLoader) load(dir, href string) *css.Stylesheet {
+	path, err := localPath(dir, href)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("cannot load stylesheet %q: %v", href, err))
+		return nil
+	}
+	if l.importing[path] {
+		l.errs = append(l.errs, fmt.Errorf("cannot import stylesheet %q: import cycle", href))
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("cannot open stylesheet: %v", err))
+		return nil
+	}
+	defer f.Close()
+
+	l.importing[path] = true
+	defer delete(l.importing, path)
+	return l.parse(href, f, filepath.Dir(path))
+}
+
+// parse parses a stylesheet whose URLs are relative to dir, and loads the stylesheets it imports.
+// The URLs of its font faces are resolved to local paths.
+func (l *stylesheetLoader) parse(name string, r io.Reader, dir string) *css.Stylesheet {
+	p := css.NewParser(r)
+	sheet := p.ParseStylesheet()
+	for _, e := range p.Errors() {
+		l.errs = append(l.errs, fmt.Errorf("%s: parsing error: %s", name, e))
+	}
+
+	for i, face := range sheet.FontFaces {
+		sources := make([]css.FontSource, len(face.Sources))
+		for j, source := range face.Sources {
+			if source.URL != "" {
+				if path, err := localPath(dir, source.URL); err == nil {
+					source.URL = path
+				}
+			}
+			sources[j] = source
+		}
+		sheet.FontFaces[i].Sources = sources
+	}
+
+	l.resolveImports(sheet, dir)
+	return sheet
+}
+
+// resolveImports adds the rules and the font faces of the stylesheets imported by sheet before its own,
+// in order. They get the supports() condition and the media queries of their @import.
+func (l *stylesheetLoader) resolveImports(sheet *css.Stylesheet, dir string) {
+	if len(sheet.Imports) == 0 {
+		return
+	}
+
+	var (
+		rules     = []css.Rule{}
+		fontFaces []css.FontFace
+	)
+	for _, imp := range sheet.Imports {
+		imported := l.load(dir, imp.URL)
+		if imported == nil {
+			continue
+		}
+		for _, rule := range imported.Rules {
+			rule.Conditions = importConditions(imp, rule.Conditions)
+			rules = append(rules, rule)
+		}
+		for _, face := range imported.FontFaces {
+			face.Conditions = importConditions(imp, face.Conditions)
+			fontFaces = append(fontFaces, face)
+		}
+	}
+	sheet.Rules = append(rules, sheet.Rules...)
+	sheet.FontFaces = append(fontFaces, sheet.FontFaces...)
+}
+
+// importConditions returns the conditions of an imported rule, within the ones of its @import.
+func importConditions(imp css.Import, conditions css.Conditions) css.Conditions {
+	if imp.Supports != nil {
+		conditions.Supports = append([]*css.SupportsCondition{imp.Supports}, conditions.Supports...)
+	}
+	if len(imp.Media) > 0 {
+		conditions.Media = append([]css.MediaQueryList{imp.Media}, conditions.Media...)
+	}
+	return conditions
+}
+
+// localPath returns the path of the local file referenced by href, relative to dir.
+func localPath(dir, href string) (string, error) {
+	if strings.Contains(href, "://") && !strings.HasPrefix(href, "file://") {
+		return "", fmt.Errorf("only local files are supported")
+	}
+	path := strings.TrimPrefix(href, "file://")
+	// drop the query and the fragment of the URL
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+	}
+	return filepath.Abs(path)
+}
@@ -0,0 +1,57 @@
+package style
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/font"
+)
+
+// LoadFontFaces adds the fonts of the @font-face rules of a stylesheet to fonts, under their family.
+// The font faces of @media and @supports rules are only loaded if their conditions are true.
+// The first source of a font face which can be loaded is used: a local TrueType-outline TTF/OTF file,
+// or a family already in fonts with local().
+// Font faces without a source to load are skipped, and reported with the reasons of their sources.
+func LoadFontFaces(stylesheet *css.Stylesheet, device css.Device, fonts *font.Collection) []error {
+	var errs []error
+	for _, face := range stylesheet.FontFaces {
+		if !face.MatchesMedia(device) || !face.MatchesSupports(supportsDeclaration) {
+			continue
+		}
+		if err := loadFontFace(face, fonts); err != nil {
+			errs = append(errs, fmt.Errorf("cannot load font face %q: %v", face.Family, err))
+		}
+	}
+	return errs
+}
+
+func loadFontFace(face css.FontFace, fonts *font.Collection) error {
+	var reasons []string
+	for _, source := range face.Sources {
+		if source.Local != "" {
+			if fonts.Alias(face.Family, source.Local, face.Weight, face.Style) {
+				return nil
+			}
+			reasons = append(reasons, fmt.Sprintf("no local font %q", source.Local))
+			continue
+		}
+
+		switch source.Format {
+		case "", "truetype", "opentype":
+		default:
+			reasons = append(reasons, fmt.Sprintf("unsupported format %q", source.Format))
+			continue
+		}
+		path, err := localPath("", source.URL)
+		if err == nil {
+			err = fonts.LoadFace(path, face.Family, face.Weight, face.Style)
+		}
+		if err == nil {
+			return nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return errors.New(strings.Join(reasons, ", "))
+}
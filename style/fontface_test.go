@@ -0,0 +1,67 @@
+package style
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lysrt/bro/css"
+	"github.com/lysrt/bro/font"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+func TestLoadFontFaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "fonts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "fonts", "mono.ttf"), gomono.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "fonts.css")
+	err = ioutil.WriteFile(path, []byte(`
+		@font-face {
+			font-family: Brand;
+			src: url(fonts/mono.woff2) format("woff2"), url(fonts/mono.ttf) format("truetype");
+		}
+		@font-face { font-family: Code; src: local(missing), local("Go Mono"); font-style: italic; }
+		@font-face { font-family: Broken; src: url(fonts/missing.ttf); }
+		@media print {
+			@font-face { font-family: Print; src: url(fonts/mono.ttf); }
+		}
+	`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheet, errs := LoadStylesheet(path)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	fonts := font.NewCollection()
+	errs = LoadFontFaces(sheet, css.Device{}, fonts)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "Broken") {
+		t.Errorf("expected an error for the Broken font face, got %v", errs)
+	}
+
+	monospace := func(family, style string) bool {
+		face := fonts.Face(family, "normal", style, 16)
+		return face.Width("iii") == face.Width("mmm")
+	}
+	if !monospace("Brand", "normal") {
+		t.Error("expected the Brand family to use the loaded font file")
+	}
+	if !monospace("Code", "italic") {
+		t.Error("expected the Code family to use the local Go Mono font")
+	}
+	// the font faces of @media print are not loaded on screen
+	if monospace("Print", "normal") {
+		t.Error("expected the Print family to fall back to the bundled font")
+	}
+}
@@ -0,0 +1,353 @@
+package style
+
+import (
+	"strings"
+
+	"github.com/lysrt/bro/css"
+)
+
+// shorthand describes a property setting several other properties at once, like margin.
+type shorthand struct {
+	longhands []string
+
+	// expand returns the values of the longhands, in order, or false if the value is invalid.
+	// The longhands the value omits are set to omitted.
+	expand func(value css.Value) ([]css.Value, bool)
+}
+
+// omitted is the value of the longhands a shorthand value omits, reset to their initial value.
+var omitted = css.Value{Keyword: "initial"}
+
+// shorthands are the shorthand properties expanded before the cascade,
+// so that layout and paint only see longhands.
+// See https://www.w3.org/TR/css-cascade-4/#shorthand
+var shorthands = map[string]shorthand{
+	"margin":        {sides("margin-%s"), expandBox},
+	"padding":       {sides("padding-%s"), expandBox},
+	"border-width":  {sides("border-%s-width"), expandBox},
+	"border-style":  {sides("border-%s-style"), expandBox},
+	"border-color":  {sides("border-%s-color"), expandBox},
+	"border-top":    {borderSide("top"), expandBorderSide},
+	"border-right":  {borderSide("right"), expandBorderSide},
+	"border-bottom": {borderSide("bottom"), expandBorderSide},
+	"border-left":   {borderSide("left"), expandBorderSide},
+	"border": {
+		append(append(sides("border-%s-width"), sides("border-%s-style")...), sides("border-%s-color")...),
+		expandBorder,
+	},
+	"font": {
+		[]string{"font-style", "font-variant", "font-weight", "font-size", "line-height", "font-family"},
+		expandFont,
+	},
+	"background": {
+		[]string{"background-color", "background-image", "background-repeat", "background-attachment", "background-position"},
+		expandBackground,
+	},
+}
+
+// boxSides are the sides of a box, in the order of the values of `margin: top right bottom left`.
+var boxSides = []string{"top", "right", "bottom", "left"}
+
+// sides returns the names of the longhands of the four sides, from a pattern like "margin-%s".
+func sides(pattern string) []string {
+	names := make([]string, len(boxSides))
+	for i, side := range boxSides {
+		names[i] = strings.Replace(pattern, "%s", side, 1)
+	}
+	return names
+}
+
+func borderSide(side string) []string {
+	return []string{"border-" + side + "-width", "border-" + side + "-style", "border-" + side + "-color"}
+}
+
+// isCSSWideKeyword reports whether a value is one of the keywords every property accepts.
+func isCSSWideKeyword(v css.Value) bool {
+	switch strings.ToLower(v.Keyword) {
+	case "inherit", "initial", "unset", "revert":
+		return true
+	}
+	return false
+}
+
+// expandShorthands replaces the shorthand declarations of a list by their longhands.
+// An invalid shorthand is dropped, like any invalid declaration.
+func expandShorthands(declarations []css.Declaration) []css.Declaration {
+	var expanded []css.Declaration
+	for _, d := range declarations {
+		s, ok := shorthands[d.Name]
+		if !ok {
+			expanded = append(expanded, d)
+			continue
+		}
+
+		var values []css.Value
+		if d.Value.Raw != nil {
+			// the longhands are expanded once the variables are substituted, see substituteVariables
+			values = make([]css.Value, len(s.longhands))
+			for i := range values {
+				values[i] = css.Value{Raw: &css.RawValue{Tokens: d.Value.Raw.Tokens, Shorthand: d.Name}}
+			}
+		} else if isCSSWideKeyword(d.Value) {
+			// `margin: inherit` is `inherit` for all the margins
+			values = make([]css.Value, len(s.longhands))
+			for i := range values {
+				values[i] = d.Value
+			}
+		} else if values, ok = s.expand(d.Value); !ok {
+			continue
+		}
+
+		for i, name := range s.longhands {
+			expanded = append(expanded, css.Declaration{Name: name, Value: values[i], Important: d.Important})
+		}
+	}
+	return expanded
+}
+
+// expandLonghand returns the value a shorthand value gives to one of its longhands.
+func expandLonghand(shorthand, longhand string, value css.Value) (css.Value, bool) {
+	if isCSSWideKeyword(value) {
+		return value, true
+	}
+	s := shorthands[shorthand]
+	values, ok := s.expand(value)
+	if !ok {
+		return css.Value{}, false
+	}
+	for i, name := range s.longhands {
+		if name == longhand {
+			return values[i], true
+		}
+	}
+	return css.Value{}, false
+}
+
+// components returns the space separated components of a value.
+func components(v css.Value) []css.Value {
+	if v.List != nil && !v.List.Comma {
+		return v.List.Values
+	}
+	return []css.Value{v}
+}
+
+// expandBox expands one to four values to the four sides: `4px 8px` sets 4px to top and bottom,
+// and 8px to right and left.
+func expandBox(v css.Value) ([]css.Value, bool) {
+	values := components(v)
+	for _, c := range values {
+		if c.List != nil || c.Text != "" {
+			return nil, false
+		}
+	}
+
+	switch len(values) {
+	case 1:
+		return []css.Value{values[0], values[0], values[0], values[0]}, true
+	case 2:
+		return []css.Value{values[0], values[1], values[0], values[1]}, true
+	case 3:
+		return []css.Value{values[0], values[1], values[2], values[1]}, true
+	case 4:
+		return values, true
+	}
+	return nil, false
+}
+
+var borderStyles = map[string]bool{
+	"none": true, "hidden": true, "dotted": true, "dashed": true, "solid": true,
+	"double": true, "groove": true, "ridge": true, "inset": true, "outset": true,
+}
+
+var borderWidths = map[string]bool{
+	"thin": true, "medium": true, "thick": true,
+}
+
+// expandBorderSide expands a width, a style and a color, in any order: `1px solid #333`.
+func expandBorderSide(v css.Value) ([]css.Value, bool) {
+	width, style, color := omitted, omitted, omitted
+	// each longhand can be given once
+	set := func(longhand *css.Value, c css.Value) bool {
+		if *longhand != omitted {
+			return false
+		}
+		*longhand = c
+		return true
+	}
+
+	for _, c := range components(v) {
+		keyword := strings.ToLower(c.Keyword)
+		ok := false
+		switch {
+		case c.List != nil || c.Text != "":
+		case isLength(c) || borderWidths[keyword]:
+			ok = set(&width, c)
+		case borderStyles[keyword]:
+			ok = set(&style, c)
+		default:
+			ok = set(&color, c)
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return []css.Value{width, style, color}, true
+}
+
+// expandBorder expands a border side value to the four sides.
+func expandBorder(v css.Value) ([]css.Value, bool) {
+	side, ok := expandBorderSide(v)
+	if !ok {
+		return nil, false
+	}
+	var values []css.Value
+	for _, longhand := range side {
+		values = append(values, longhand, longhand, longhand, longhand)
+	}
+	return values, true
+}
+
+// expandFont expands `[style || variant || weight] size[/line-height] family`,
+// like `italic bold 12px/1.5 "Go Mono", monospace`.
+func expandFont(v css.Value) ([]css.Value, bool) {
+	groups := []css.Value{v}
+	if v.List != nil && v.List.Comma {
+		groups = v.List.Values
+	}
+	values := components(groups[0])
+
+	style, variant, weight, lineHeight := omitted, omitted, omitted, omitted
+	i := 0
+prefix:
+	for ; i < len(values); i++ {
+		c := values[i]
+		keyword := strings.ToLower(c.Keyword)
+		switch {
+		case keyword == "normal":
+			// normal is the initial value of the three properties
+		case keyword == "italic" || keyword == "oblique":
+			style = c
+		case keyword == "small-caps":
+			variant = c
+		case keyword == "bold" || keyword == "bolder" || keyword == "lighter":
+			weight = c
+		case c.Keyword == "" && c.Length.Unit == "" && c.Length.Quantity >= 1 && c.Length.Quantity <= 1000:
+			weight = c
+		default:
+			break prefix
+		}
+	}
+
+	if i == len(values) {
+		return nil, false
+	}
+	size := values[i]
+	keyword := strings.ToLower(size.Keyword)
+	if size.Keyword != "" && keyword != "larger" && keyword != "smaller" {
+		if _, ok := fontSizeKeywords[keyword]; !ok {
+			return nil, false
+		}
+	}
+	if size.Keyword == "" && size.Math == nil && (size.Length.Unit == "" || size.Function != nil || size.Text != "") {
+		return nil, false
+	}
+	i++
+
+	if i < len(values) && values[i].Keyword == css.SLASH {
+		if i+1 == len(values) {
+			return nil, false
+		}
+		lineHeight = values[i+1]
+		i += 2
+	}
+
+	// the family is the rest of the value: names made of several words, and the names after the commas
+	family := values[i:]
+	if len(family) == 0 {
+		return nil, false
+	}
+	var families []css.Value
+	if len(family) == 1 {
+		families = append(families, family[0])
+	} else {
+		families = append(families, css.Value{List: &css.ValueList{Values: family}})
+	}
+	families = append(families, groups[1:]...)
+
+	fontFamily := families[0]
+	if len(families) > 1 {
+		fontFamily = css.Value{List: &css.ValueList{Values: families, Comma: true}}
+	}
+	return []css.Value{style, variant, weight, size, lineHeight, fontFamily}, true
+}
+
+var backgroundRepeats = map[string]bool{
+	"repeat": true, "repeat-x": true, "repeat-y": true, "no-repeat": true, "space": true, "round": true,
+}
+
+var backgroundAttachments = map[string]bool{
+	"scroll": true, "fixed": true, "local": true,
+}
+
+var backgroundPositions = map[string]bool{
+	"left": true, "right": true, "top": true, "bottom": true, "center": true,
+}
+
+var backgroundSizes = keywords("auto", "cover", "contain")
+
+var backgroundBoxes = map[string]bool{
+	"border-box": true, "padding-box": true, "content-box": true,
+}
+
+// expandBackground expands the color, image, repeat, attachment and position of a background,
+// like `#eee url(bg.png) no-repeat left top`. With several layers, the color is in the last one.
+func expandBackground(v css.Value) ([]css.Value, bool) {
+	layer := v
+	if v.List != nil && v.List.Comma {
+		layer = v.List.Values[len(v.List.Values)-1]
+	}
+
+	color, image := omitted, omitted
+	var repeat, attachment, position []css.Value
+	values := components(layer)
+	for i := 0; i < len(values); i++ {
+		c := values[i]
+		keyword := strings.ToLower(c.Keyword)
+		switch {
+		case c.List != nil || c.Text != "":
+			return nil, false
+		case keyword == "none" || c.Function != nil && (c.Function.Name == "url" || strings.HasSuffix(c.Function.Name, "gradient")):
+			image = c
+		case backgroundRepeats[keyword]:
+			repeat = append(repeat, c)
+		case backgroundAttachments[keyword]:
+			attachment = append(attachment, c)
+		case backgroundPositions[keyword] || isLength(c):
+			position = append(position, c)
+		case c.Keyword == css.SLASH:
+			// the background-size after the position is not supported, and skipped
+			for i+1 < len(values) && (isLength(values[i+1]) || backgroundSizes(values[i+1])) {
+				i++
+			}
+		case backgroundBoxes[keyword]:
+			// background-origin and background-clip are not supported
+		default:
+			if color != omitted {
+				return nil, false
+			}
+			color = c
+		}
+	}
+	return []css.Value{color, image, join(repeat), join(attachment), join(position)}, true
+}
+
+// join returns a value made of space separated components, or omitted if there are none.
+func join(values []css.Value) css.Value {
+	switch len(values) {
+	case 0:
+		return omitted
+	case 1:
+		return values[0]
+	}
+	return css.Value{List: &css.ValueList{Values: values}}
+}
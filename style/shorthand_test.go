@@ -0,0 +1,165 @@
+package style
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lysrt/bro/css"
+)
+
+func Test_expandShorthands(t *testing.T) {
+	auto := css.Value{Keyword: "auto"}
+	initial := css.Value{Keyword: "initial"}
+	solid := css.Value{Keyword: "solid"}
+	gray := css.Value{Color: css.Color{A: 255, R: 0x33, G: 0x33, B: 0x33}}
+	transparent := css.Value{Color: css.Transparent}
+
+	tests := []struct {
+		declarations string
+		want         PropertyMap
+	}{
+		{
+			"margin: 0 auto",
+			PropertyMap{"margin-top": {}, "margin-right": auto, "margin-bottom": {}, "margin-left": auto},
+		},
+		{
+			"padding: 4px 8px 2px",
+			PropertyMap{"padding-top": px(4), "padding-right": px(8), "padding-bottom": px(2), "padding-left": px(8)},
+		},
+		{
+			"padding: 1px 2px 3px 4px; padding-left: 5px",
+			PropertyMap{"padding-top": px(1), "padding-right": px(2), "padding-bottom": px(3), "padding-left": px(5)},
+		},
+		{
+			"margin: 1px 2px 3px 4px 5px",
+			PropertyMap{},
+		},
+		{
+			"border-top: #333 solid",
+			PropertyMap{"border-top-width": initial, "border-top-style": solid, "border-top-color": gray},
+		},
+		{
+			"border: 1px solid #333",
+			PropertyMap{
+				"border-top-width": px(1), "border-right-width": px(1), "border-bottom-width": px(1), "border-left-width": px(1),
+				"border-top-style": solid, "border-right-style": solid, "border-bottom-style": solid, "border-left-style": solid,
+				"border-top-color": gray, "border-right-color": gray, "border-bottom-color": gray, "border-left-color": gray,
+			},
+		},
+		{
+			"border: 1px 2px solid",
+			PropertyMap{},
+		},
+		{
+			"border: 1px solid #0000",
+			PropertyMap{
+				"border-top-width": px(1), "border-right-width": px(1), "border-bottom-width": px(1), "border-left-width": px(1),
+				"border-top-style": solid, "border-right-style": solid, "border-bottom-style": solid, "border-left-style": solid,
+				"border-top-color": transparent, "border-right-color": transparent,
+				"border-bottom-color": transparent, "border-left-color": transparent,
+			},
+		},
+		{
+			"border-left: 0 rgba(0, 0, 0, 0) solid",
+			PropertyMap{"border-left-width": {}, "border-left-style": solid, "border-left-color": transparent},
+		},
+		{
+			"margin: inherit",
+			PropertyMap{
+				"margin-top": {Keyword: "inherit"}, "margin-right": {Keyword: "inherit"},
+				"margin-bottom": {Keyword: "inherit"}, "margin-left": {Keyword: "inherit"},
+			},
+		},
+		{
+			"font: italic bold 12px/1.5 Go Mono, monospace",
+			PropertyMap{
+				"font-style":   {Keyword: "italic"},
+				"font-variant": initial,
+				"font-weight":  {Keyword: "bold"},
+				"font-size":    px(12),
+				"line-height":  {Length: css.Length{Quantity: 1.5}},
+				"font-family": {List: &css.ValueList{Comma: true, Values: []css.Value{
+					{List: &css.ValueList{Values: []css.Value{{Keyword: "Go"}, {Keyword: "Mono"}}}},
+					{Keyword: "monospace"},
+				}}},
+			},
+		},
+		{
+			`font: 600 large "Go"`,
+			PropertyMap{
+				"font-style": initial, "font-variant": initial, "font-weight": {Length: css.Length{Quantity: 600}},
+				"font-size": {Keyword: "large"}, "line-height": initial, "font-family": {Text: "Go"},
+			},
+		},
+		{
+			"font: bold 12px",
+			PropertyMap{},
+		},
+		{
+			`background: #333 url("bg.png") no-repeat left top`,
+			PropertyMap{
+				"background-color":      gray,
+				"background-image":      {Function: nil},
+				"background-repeat":     {Keyword: "no-repeat"},
+				"background-attachment": initial,
+				"background-position":   {List: &css.ValueList{Values: []css.Value{{Keyword: "left"}, {Keyword: "top"}}}},
+			},
+		},
+		{
+			`background: #0000 url("bg.png") 10px 0 / cover`,
+			PropertyMap{
+				"background-color":      transparent,
+				"background-image":      {Function: nil},
+				"background-repeat":     initial,
+				"background-attachment": initial,
+				"background-position":   {List: &css.ValueList{Values: []css.Value{px(10), {}}}},
+			},
+		},
+		{
+			"background: red",
+			PropertyMap{
+				"background-color": {Keyword: "red"}, "background-image": initial, "background-repeat": initial,
+				"background-attachment": initial, "background-position": initial,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.declarations, func(t *testing.T) {
+			p := css.NewParser(strings.NewReader(tt.declarations))
+			declarations := p.ParseDeclarations()
+			if len(p.Errors()) > 0 {
+				t.Fatal(p.Errors())
+			}
+
+			got := make(PropertyMap)
+			for _, d := range expandShorthands(declarations) {
+				got[d.Name] = d.Value
+			}
+			if image, ok := got["background-image"]; ok && image.Function != nil {
+				// compared apart, as the function is a pointer
+				if image.Function.Name != "url" || image.Function.Args[0].Text != "bg.png" {
+					t.Errorf("background-image = %v", image.Function)
+				}
+				got["background-image"] = css.Value{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandShorthands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_expandShorthands_important(t *testing.T) {
+	declarations := expandShorthands([]css.Declaration{
+		{Name: "padding", Value: px(1), Important: true},
+	})
+	if len(declarations) != 4 {
+		t.Fatalf("expected 4 longhands, got %v", declarations)
+	}
+	for _, d := range declarations {
+		if !d.Important {
+			t.Errorf("%s is not important", d.Name)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package style
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/lysrt/bro/css"
@@ -17,14 +18,24 @@ type PropertyMap map[string]css.Value
 
 // StyledNode represents a DOM Node with the associated CSS.
 type StyledNode struct {
-	Node            *html.Node
+	Node *html.Node
+
+	// SpecifiedValues holds the values the cascade gives to the node
 	SpecifiedValues PropertyMap
-	Children        []*StyledNode
+	// ComputedValues holds the values used by layout and paint, after inheritance
+	// and the resolution of relative values like em, see computeValues
+	ComputedValues PropertyMap
+
+	Children []*StyledNode
+
+	// PseudoElement is "before" or "after" for the nodes generated by the content property.
+	// Their Node is not part of the DOM.
+	PseudoElement string
 }
 
-// Value returns the value of a given CSS property name of a StyleNode, if it has one
+// Value returns the computed value of a given CSS property name of a StyleNode, if it has one
 func (node *StyledNode) Value(property string) (value css.Value, ok bool) {
-	value, ok = node.SpecifiedValues[property]
+	value, ok = node.ComputedValues[property]
 	return
 }
 
@@ -38,13 +49,20 @@ const (
 
 // Display returns the CSS display type of a StyledNode
 func (node *StyledNode) Display() Display {
+	if node.Node.Type == html.NodeText {
+		return Inline // Text always flows inside an inline formatting context
+	}
+
 	value, ok := node.Value("display")
 	if !ok {
+		if node.PseudoElement != "" {
+			return Inline // Generated content flows with the content of its element
+		}
 		return Block // Block is the default display type
 	}
 
 	switch strings.ToLower(value.Keyword) {
-	case "block":
+	case "block", "list-item": // list items are laid out as blocks, without marker
 		return Block
 	case "none":
 		return None
@@ -53,30 +71,96 @@ func (node *StyledNode) Display() Display {
 	}
 }
 
+// isDisplayNone reports whether computed values have display: none, the element generating no box.
+func isDisplayNone(computed PropertyMap) bool {
+	return strings.EqualFold(computed["display"].Keyword, "none")
+}
+
 // GenerateStyleTree a DOM node and its children with CSS rules from a Stylesheet.
-func GenerateStyleTree(root *html.Node, css *css.Stylesheet) *StyledNode {
-	var propertyMap PropertyMap
+// The rules of the user-agent stylesheet apply below the given ones.
+// The rules of @media rules only apply if their media queries match the device,
+// and the rules of @supports rules if the engine supports their condition.
+func GenerateStyleTree(root *html.Node, stylesheet *css.Stylesheet, device css.Device) *StyledNode {
+	b := &treeBuilder{
+		stylesheet: applicableRules(css.Merge(userAgentStylesheet(), stylesheet), device),
+		counters:   newCounters(),
+		lengths: css.LengthContext{
+			RootFontSize:   mediumFontSize,
+			ViewportWidth:  device.Width,
+			ViewportHeight: device.Height,
+		},
+	}
+	return b.generateStyleTree(root, nil)
+}
 
-	switch root.Type {
-	case html.NodeElement:
-		if css == nil {
-			propertyMap = make(PropertyMap)
-		} else {
-			propertyMap = specifiedValues(root, css)
+// applicableRules returns the rules of a stylesheet whose @media and @supports conditions are true.
+func applicableRules(stylesheet *css.Stylesheet, device css.Device) *css.Stylesheet {
+	matching := &css.Stylesheet{Rules: []css.Rule{}}
+	for _, rule := range stylesheet.Rules {
+		if rule.MatchesMedia(device) && rule.MatchesSupports(supportsDeclaration) {
+			matching.Rules = append(matching.Rules, rule)
 		}
-	case html.NodeText:
-		propertyMap = make(PropertyMap)
+	}
+	return matching
+}
+
+// treeBuilder holds the state needed while the style tree is generated in document order.
+type treeBuilder struct {
+	stylesheet *css.Stylesheet
+	counters   *counters
+	// hidden is set inside an element with display: none, whose elements are not rendered
+	hidden bool
+
+	// lengths holds the viewport, and the computed font size of the root element for rem.
+	// The root element itself is relative to the initial font size.
+	lengths css.LengthContext
+}
+
+func (b *treeBuilder) generateStyleTree(root *html.Node, parent PropertyMap) *StyledNode {
+	specified := make(PropertyMap)
+	if root.Type == html.NodeElement {
+		specified = specifiedValues(root, b.stylesheet)
+	}
+	computed := computeValues(specified, parent, b.lengths)
+	if value, ok := computed["font-size"]; ok && parent == nil {
+		b.lengths.RootFontSize = value.Length.Quantity
 	}
 
 	var children []*StyledNode
+	if root.Type == html.NodeElement && !b.hidden && isDisplayNone(computed) {
+		// the elements which are not rendered neither reset nor increment counters, nor generate content
+		b.hidden = true
+		defer func() { b.hidden = false }()
+	}
+	if root.Type == html.NodeElement && !b.hidden {
+		b.counters.update(computed)
+		// the counters created by the children are only visible inside the element
+		b.counters.enter()
+		defer b.counters.leave()
+
+		if before := b.generatePseudoElement(root, computed, "before"); before != nil {
+			children = append(children, before)
+		}
+	}
+
 	for _, child := range html.NodeChildren(root) {
-		styled := GenerateStyleTree(child, css)
+		if child.Type == html.NodeComment {
+			continue
+		}
+		styled := b.generateStyleTree(child, computed)
 		children = append(children, styled)
 	}
 
+	if root.Type == html.NodeElement && !b.hidden {
+		if after := b.generatePseudoElement(root, computed, "after"); after != nil {
+			children = append(children, after)
+		}
+	}
+
 	return &StyledNode{
 		Node:            root,
-		SpecifiedValues: propertyMap,
+		SpecifiedValues: specified,
+		ComputedValues:  computed,
 		Children:        children,
 	}
 }
@@ -85,57 +169,119 @@ func GenerateStyleTree(root *html.Node, css *css.Stylesheet) *StyledNode {
 type MatchedRule struct {
 	Rule        css.Rule
 	Specificity css.Specificity
+
+	// Inline is set for the declarations of the style attribute,
+	// which win over the author rules whatever their specificity
+	Inline bool
 }
 
 // specifiedValues returns a map of all CSS properties applied to a given DOM node.
 func specifiedValues(element *html.Node, stylesheet *css.Stylesheet) PropertyMap {
-	properties := make(PropertyMap)
-	rules := matchingRules(element, stylesheet)
+	rules := matchingRules(element, stylesheet, "")
+	if declarations := inlineDeclarations(element); len(declarations) > 0 {
+		rules = append(rules, MatchedRule{
+			Rule:   css.Rule{Declarations: declarations, Origin: css.Author},
+			Inline: true,
+		})
+	}
+	return cascade(rules)
+}
+
+// cascadedDeclaration is a declaration with what the cascade needs to order it.
+type cascadedDeclaration struct {
+	css.Declaration
+	rule *MatchedRule
+}
+
+// precedence returns the rank of a declaration between the cascade origins:
+// normal user-agent, user and author declarations, then important author, user and user-agent ones.
+// It relies on css.Origin values going from the author to the user-agent.
+func (d cascadedDeclaration) precedence() int {
+	origin := d.rule.Rule.Origin
+	if d.Important {
+		return 3 + int(origin)
+	}
+	return 2 - int(origin)
+}
+
+// less reports whether d loses against o in the cascade.
+func (d cascadedDeclaration) less(o cascadedDeclaration) bool {
+	if p, q := d.precedence(), o.precedence(); p != q {
+		return p < q
+	}
+	if d.rule.Inline != o.rule.Inline {
+		return o.rule.Inline
+	}
+	return d.rule.Specificity.Less(o.rule.Specificity)
+}
 
-	// Order from lowest to highest specificity
+// cascade returns the properties declared by a list of matched rules,
+// ordering their declarations by origin and importance, specificity and source order.
+// The shorthand properties are replaced by their longhands.
+// See https://www.w3.org/TR/css-cascade-4/#cascade-sort
+func cascade(rules []MatchedRule) PropertyMap {
+	var declarations []cascadedDeclaration
 	for i := range rules {
-		for j := range rules[i:] {
-			speI := rules[i].Specificity
-			speJ := rules[j].Specificity
-			if speI.A > speJ.A {
-				continue
-			}
-			if speI.A < speJ.A {
-				rules[i], rules[j] = rules[j], rules[i]
-				continue
-			}
-			if speI.B > speJ.B {
-				continue
-			}
-			if speI.B < speJ.B {
-				rules[i], rules[j] = rules[j], rules[i]
-				continue
-			}
-			if speI.C > speJ.C {
-				continue
-			}
-			if speI.C < speJ.C {
-				rules[i], rules[j] = rules[j], rules[i]
-				continue
-			}
+		for _, d := range expandShorthands(rules[i].Rule.Declarations) {
+			declarations = append(declarations, cascadedDeclaration{Declaration: d, rule: &rules[i]})
 		}
 	}
 
-	// If several rules have the same name, highest specificity rules will override low specificity ones
-	for _, r := range rules {
-		for _, d := range r.Rule.Declarations {
-			properties[d.Name] = d.Value
-		}
+	// Order from lowest to highest precedence.
+	// The sort is stable, so that the last declaration wins between equal precedences.
+	sort.SliceStable(declarations, func(i, j int) bool {
+		return declarations[i].less(declarations[j])
+	})
+
+	// If several declarations have the same name, the last one overrides the others
+	byName := make(map[string][]cascadedDeclaration)
+	for _, d := range declarations {
+		byName[d.Name] = append(byName[d.Name], d)
+	}
+	properties := make(PropertyMap)
+	for name, declarations := range byName {
+		properties[name] = cascadedValue(declarations)
 	}
 
 	return properties
 }
 
-// matchingRules returns all the matched CSS rules for a given DOM node.
-func matchingRules(n *html.Node, stylesheet *css.Stylesheet) []MatchedRule {
+// cascadedValue returns the winning value among the declarations of a property, sorted by precedence.
+// The revert keyword rolls the cascade back to the declarations of the origins below its own,
+// and acts as unset in the user-agent origin.
+func cascadedValue(declarations []cascadedDeclaration) css.Value {
+	if len(declarations) == 0 {
+		return css.Value{Keyword: "unset"}
+	}
+	winner := declarations[len(declarations)-1]
+	if !strings.EqualFold(winner.Value.Keyword, "revert") {
+		return winner.Value
+	}
+
+	var lower []cascadedDeclaration
+	for _, d := range declarations {
+		if d.rule.Rule.Origin > winner.rule.Rule.Origin {
+			lower = append(lower, d)
+		}
+	}
+	return cascadedValue(lower)
+}
+
+// inlineDeclarations returns the declarations of the style attribute of a DOM node.
+func inlineDeclarations(n *html.Node) []css.Declaration {
+	attr, ok := n.Attributes["style"]
+	if !ok || strings.TrimSpace(attr) == "" {
+		return nil
+	}
+	return css.NewParser(strings.NewReader(attr)).ParseDeclarations()
+}
+
+// matchingRules returns all the matched CSS rules for a given DOM node,
+// or for one of its pseudo-elements.
+func matchingRules(n *html.Node, stylesheet *css.Stylesheet, pseudoElement string) []MatchedRule {
 	var matches []MatchedRule
 	for _, r := range stylesheet.Rules {
-		m, ok := matchRule(n, r)
+		m, ok := matchPseudoElementRule(n, r, pseudoElement)
 		if !ok {
 			continue
 		}
@@ -146,26 +292,69 @@ func matchingRules(n *html.Node, stylesheet *css.Stylesheet) []MatchedRule {
 
 // matchRule tries to match a CSS rule to a DOM node and returns the most specific one.
 func matchRule(n *html.Node, rule css.Rule) (m MatchedRule, ok bool) {
+	return matchPseudoElementRule(n, rule, "")
+}
+
+// matchPseudoElementRule tries to match a CSS rule to a pseudo-element of a DOM node,
+// or to the node itself when pseudoElement is empty.
+func matchPseudoElementRule(n *html.Node, rule css.Rule, pseudoElement string) (m MatchedRule, ok bool) {
 	for _, s := range rule.Selectors {
-		if matchSelector(n, s) {
-			ok = true
+		if s.PseudoElement != pseudoElement || !matchSelector(n, s) {
+			continue
+		}
+		// the rule applies with the specificity of its most specific matching selector
+		if specificity := s.Specificity(); !ok || m.Specificity.Less(specificity) {
 			m = MatchedRule{
 				Rule:        rule,
-				Specificity: s.Specificity(),
+				Specificity: specificity,
 			}
-			return
 		}
+		ok = true
 	}
 	return
 }
 
 // matchSelector tries to match a DOM node with a CSS selector.
-// There is a match only if all the fields of the selector match.
+// The compound selector must match the node, and its relative selectors
+// the nodes around it, as given by the combinators.
 func matchSelector(n *html.Node, selector css.Selector) bool {
-	if selector.TagName != "" && selector.TagName == "*" {
+	if !matchCompound(n, selector) {
+		return false
+	}
+	if selector.Relative == nil {
 		return true
 	}
-	if selector.TagName != "" && n.Tag != selector.TagName {
+
+	relative := *selector.Relative
+	switch selector.Combinator {
+	case css.Descendant:
+		for p := parentElement(n); p != nil; p = parentElement(p) {
+			if matchSelector(p, relative) {
+				return true
+			}
+		}
+	case css.Child:
+		if p := parentElement(n); p != nil {
+			return matchSelector(p, relative)
+		}
+	case css.NextSibling:
+		if s := html.NodePrevElementSibling(n); s != nil {
+			return matchSelector(s, relative)
+		}
+	case css.SubsequentSibling:
+		for s := html.NodePrevElementSibling(n); s != nil; s = html.NodePrevElementSibling(s) {
+			if matchSelector(s, relative) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchCompound tries to match a DOM node with a compound selector, ignoring its relatives.
+// There is a match only if all the fields of the selector match.
+func matchCompound(n *html.Node, selector css.Selector) bool {
+	if selector.TagName != "" && selector.TagName != "*" && n.Tag != selector.TagName {
 		return false
 	}
 	if selector.ID != "" && selector.ID != html.NodeGetID(n) {
@@ -181,5 +370,160 @@ outer_loop:
 		// The CSS selector class is not one of the DOM node classes
 		return false
 	}
+	for _, a := range selector.Attributes {
+		if !matchAttribute(n, a) {
+			return false
+		}
+	}
+	for _, p := range selector.PseudoClasses {
+		if !matchPseudoClass(n, p) {
+			return false
+		}
+	}
 	return true
 }
+
+// matchPseudoClass tries to match a DOM node with a pseudo-class.
+func matchPseudoClass(n *html.Node, pseudo css.PseudoClass) bool {
+	sameType := func(s *html.Node) bool { return s.Tag == n.Tag }
+
+	switch pseudo.Name {
+	case "first-child":
+		return html.NodePrevElementSibling(n) == nil
+	case "last-child":
+		return html.NodeNextElementSibling(n) == nil
+	case "only-child":
+		return html.NodePrevElementSibling(n) == nil && html.NodeNextElementSibling(n) == nil
+	case "first-of-type":
+		return siblingIndex(n, html.NodePrevElementSibling, sameType) == 1
+	case "last-of-type":
+		return siblingIndex(n, html.NodeNextElementSibling, sameType) == 1
+	case "only-of-type":
+		return siblingIndex(n, html.NodePrevElementSibling, sameType) == 1 &&
+			siblingIndex(n, html.NodeNextElementSibling, sameType) == 1
+	case "nth-child", "nth-last-child":
+		filter := func(*html.Node) bool { return true }
+		if len(pseudo.Selectors) > 0 {
+			// :nth-child(An+B of S) counts only the siblings matching S
+			filter = func(s *html.Node) bool { return matchAny(s, pseudo.Selectors) }
+			if !filter(n) {
+				return false
+			}
+		}
+		sibling := html.NodePrevElementSibling
+		if pseudo.Name == "nth-last-child" {
+			sibling = html.NodeNextElementSibling
+		}
+		return matchNth(pseudo.A, pseudo.B, siblingIndex(n, sibling, filter))
+	case "nth-of-type":
+		return matchNth(pseudo.A, pseudo.B, siblingIndex(n, html.NodePrevElementSibling, sameType))
+	case "nth-last-of-type":
+		return matchNth(pseudo.A, pseudo.B, siblingIndex(n, html.NodeNextElementSibling, sameType))
+	case "not":
+		return !matchAny(n, pseudo.Selectors)
+	case "is", "where":
+		return matchAny(n, pseudo.Selectors)
+	case "root":
+		return parentElement(n) == nil
+	case "empty":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.NodeComment {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// matchAny reports whether a DOM node matches one of the selectors.
+func matchAny(n *html.Node, selectors []css.Selector) bool {
+	for _, s := range selectors {
+		if matchSelector(n, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// siblingIndex returns the 1-based position of a DOM node among its siblings accepted by filter,
+// counting in the direction of the sibling function.
+func siblingIndex(n *html.Node, sibling func(*html.Node) *html.Node, filter func(*html.Node) bool) int {
+	index := 1
+	for s := sibling(n); s != nil; s = sibling(s) {
+		if filter(s) {
+			index++
+		}
+	}
+	return index
+}
+
+// matchNth reports whether index is A*n+B for some n >= 0.
+func matchNth(a, b, index int) bool {
+	if a == 0 {
+		return index == b
+	}
+	return (index-b)/a >= 0 && (index-b)%a == 0
+}
+
+// matchAttribute tries to match a DOM node attribute with an attribute selector.
+func matchAttribute(n *html.Node, selector css.AttributeSelector) bool {
+	value, ok := attribute(n, selector.Name)
+	if !ok {
+		return false
+	}
+
+	expected := selector.Value
+	if selector.CaseInsensitive {
+		value = strings.ToLower(value)
+		expected = strings.ToLower(expected)
+	}
+
+	switch selector.Operator {
+	case css.Exists:
+		return true
+	case css.Equals:
+		return value == expected
+	case css.Includes:
+		if expected == "" || strings.ContainsAny(expected, " \t\n\r\f") {
+			return false
+		}
+		for _, word := range strings.Fields(value) {
+			if word == expected {
+				return true
+			}
+		}
+		return false
+	case css.DashMatch:
+		return value == expected || strings.HasPrefix(value, expected+"-")
+	case css.PrefixMatch:
+		return expected != "" && strings.HasPrefix(value, expected)
+	case css.SuffixMatch:
+		return expected != "" && strings.HasSuffix(value, expected)
+	case css.Substring:
+		return expected != "" && strings.Contains(value, expected)
+	}
+	return false
+}
+
+// attribute returns the value of a DOM node attribute.
+// HTML attribute names are case-insensitive.
+func attribute(n *html.Node, name string) (string, bool) {
+	if value, ok := n.Attributes[name]; ok {
+		return value, true
+	}
+	for k, value := range n.Attributes {
+		if strings.EqualFold(k, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// parentElement returns the parent of a DOM node if it is an element.
+func parentElement(n *html.Node) *html.Node {
+	if n.Parent == nil || n.Parent.Type != html.NodeElement {
+		return nil
+	}
+	return n.Parent
+}
@@ -1,7 +1,11 @@
 package style
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/lysrt/bro/css"
@@ -101,6 +105,134 @@ func Test_matchSelector(t *testing.T) {
 	}
 }
 
+func Test_matchSelector_combinators(t *testing.T) {
+	nav := htmlParseSnippet(t, `<nav><h1></h1><ul><li class="a"></li><li class="b"></li><li class="c"></li></ul></nav>`)
+	ul := html.NodeLastElementChild(nav)
+	a := html.NodeFirstElementChild(ul)
+	b := html.NodeNextElementSibling(a)
+	c := html.NodeNextElementSibling(b)
+
+	tests := []struct {
+		name     string
+		n        *html.Node
+		selector css.Selector
+		want     bool
+	}{
+		{"descendant", a, css.Selector{TagName: "li", Combinator: css.Descendant, Relative: &css.Selector{TagName: "nav"}}, true},
+		{"not descendant", nav, css.Selector{TagName: "nav", Combinator: css.Descendant, Relative: &css.Selector{TagName: "ul"}}, false},
+		{"child", a, css.Selector{TagName: "li", Combinator: css.Child, Relative: &css.Selector{TagName: "ul"}}, true},
+		{"not child", a, css.Selector{TagName: "li", Combinator: css.Child, Relative: &css.Selector{TagName: "nav"}}, false},
+		{"next sibling", b, css.Selector{TagName: "li", Combinator: css.NextSibling, Relative: &css.Selector{Classes: []string{"a"}}}, true},
+		{"not next sibling", c, css.Selector{TagName: "li", Combinator: css.NextSibling, Relative: &css.Selector{Classes: []string{"a"}}}, false},
+		{"subsequent sibling", c, css.Selector{TagName: "li", Combinator: css.SubsequentSibling, Relative: &css.Selector{Classes: []string{"a"}}}, true},
+		{"not subsequent sibling", a, css.Selector{TagName: "li", Combinator: css.SubsequentSibling, Relative: &css.Selector{Classes: []string{"c"}}}, false},
+		{"sibling of parent", ul, css.Selector{TagName: "ul", Combinator: css.NextSibling, Relative: &css.Selector{TagName: "h1"}}, true},
+		{
+			"chain",
+			c,
+			css.Selector{
+				TagName:    "li",
+				Combinator: css.SubsequentSibling,
+				Relative:   &css.Selector{Classes: []string{"b"}, Combinator: css.Child, Relative: &css.Selector{TagName: "ul"}},
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchSelector(tt.n, tt.selector); got != tt.want {
+				t.Errorf("matchSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchSelector_attributes(t *testing.T) {
+	n := htmlParseSnippet(t, `<a href="https://example.com/doc.pdf" lang="en-US" data-x="a y b" type="Checkbox" title=""></a>`)
+
+	tests := []struct {
+		selector css.AttributeSelector
+		want     bool
+	}{
+		{css.AttributeSelector{Name: "href"}, true},
+		{css.AttributeSelector{Name: "title"}, true},
+		{css.AttributeSelector{Name: "target"}, false},
+		{css.AttributeSelector{Name: "type", Operator: css.Equals, Value: "Checkbox"}, true},
+		{css.AttributeSelector{Name: "type", Operator: css.Equals, Value: "checkbox"}, false},
+		{css.AttributeSelector{Name: "type", Operator: css.Equals, Value: "checkbox", CaseInsensitive: true}, true},
+		{css.AttributeSelector{Name: "data-x", Operator: css.Includes, Value: "y"}, true},
+		{css.AttributeSelector{Name: "data-x", Operator: css.Includes, Value: "a y"}, false},
+		{css.AttributeSelector{Name: "data-x", Operator: css.Includes, Value: "x"}, false},
+		{css.AttributeSelector{Name: "lang", Operator: css.DashMatch, Value: "en"}, true},
+		{css.AttributeSelector{Name: "lang", Operator: css.DashMatch, Value: "e"}, false},
+		{css.AttributeSelector{Name: "href", Operator: css.PrefixMatch, Value: "https"}, true},
+		{css.AttributeSelector{Name: "href", Operator: css.PrefixMatch, Value: ""}, false},
+		{css.AttributeSelector{Name: "href", Operator: css.SuffixMatch, Value: ".pdf"}, true},
+		{css.AttributeSelector{Name: "href", Operator: css.SuffixMatch, Value: ".PDF"}, false},
+		{css.AttributeSelector{Name: "href", Operator: css.Substring, Value: "example"}, true},
+		{css.AttributeSelector{Name: "href", Operator: css.Substring, Value: "exemple"}, false},
+		{css.AttributeSelector{Name: "HREF", Operator: css.Substring, Value: "example"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector.String(), func(t *testing.T) {
+			selector := css.Selector{Attributes: []css.AttributeSelector{tt.selector}}
+			if got := matchSelector(n, selector); got != tt.want {
+				t.Errorf("matchSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchSelector_pseudoClasses(t *testing.T) {
+	ul := htmlParseSnippet(t, `<ul><li class="a"></li><p></p><li class="b">text</li><li class="a"></li><li><!-- comment --></li></ul>`)
+	var items []*html.Node
+	for c := html.NodeFirstElementChild(ul); c != nil; c = html.NodeNextElementSibling(c) {
+		items = append(items, c)
+	}
+	root := ul.Parent.Parent
+
+	parse := func(s string) css.Selector {
+		p := css.NewParser(strings.NewReader(s))
+		return p.ParseStylesheet().Rules[0].Selectors[0]
+	}
+	tests := []struct {
+		selector string
+		want     []bool // for each child of ul
+	}{
+		{":first-child", []bool{true, false, false, false, false}},
+		{":last-child", []bool{false, false, false, false, true}},
+		{":only-child", []bool{false, false, false, false, false}},
+		{"li:first-of-type", []bool{true, false, false, false, false}},
+		{"p:only-of-type", []bool{false, true, false, false, false}},
+		{":nth-child(2n+1)", []bool{true, false, true, false, true}},
+		{":nth-child(-n+2)", []bool{true, true, false, false, false}},
+		{":nth-last-child(2)", []bool{false, false, false, true, false}},
+		{":nth-of-type(2)", []bool{false, false, true, false, false}},
+		{":nth-last-of-type(1)", []bool{false, true, false, false, true}},
+		{":nth-child(2 of li)", []bool{false, false, true, false, false}},
+		{":nth-child(odd of .a)", []bool{true, false, false, false, false}},
+		{":not(.a, p)", []bool{false, false, true, false, true}},
+		{":is(.b, p)", []bool{false, true, true, false, false}},
+		{":where(ul > .a)", []bool{true, false, false, true, false}},
+		{":empty", []bool{true, true, false, true, true}},
+		{":root li", []bool{true, false, true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector := parse(tt.selector + " { color: red; }")
+			for i, n := range items {
+				if got := matchSelector(n, selector); got != tt.want[i] {
+					t.Errorf("matchSelector(child %d) = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if !matchSelector(root, parse(":root { color: red; }")) {
+		t.Errorf("the root element should match :root")
+	}
+}
+
 func Test_matchRule(t *testing.T) {
 	type args struct {
 		n *html.Node
@@ -258,3 +390,467 @@ func Test_specifiedValues(t *testing.T) {
 		})
 	}
 }
+
+func Test_specifiedValues_inlineStyle(t *testing.T) {
+	node := htmlParseSnippet(t, `<p id="intro" style="color: green; width: 10px">text</p>`)
+	style := &css.Stylesheet{
+		Rules: []css.Rule{
+			{
+				Selectors: []css.Selector{
+					{ID: "intro"},
+				},
+				Declarations: []css.Declaration{
+					{Name: "color", Value: css.Value{Keyword: "red"}},
+					{Name: "height", Value: css.Value{Length: css.Length{Quantity: 5, Unit: css.Px}}},
+				},
+			},
+		},
+	}
+
+	want := PropertyMap{
+		"color":  css.Value{Keyword: "green"},
+		"width":  css.Value{Length: css.Length{Quantity: 10, Unit: css.Px}},
+		"height": css.Value{Length: css.Length{Quantity: 5, Unit: css.Px}},
+	}
+	if got := specifiedValues(node, style); !reflect.DeepEqual(got, want) {
+		t.Errorf("specifiedValues() = %v, want %v", got, want)
+	}
+}
+
+func Test_specifiedValues_cascadeOrder(t *testing.T) {
+	node := htmlParseSnippet(t, `<p id="a" class="x y">text</p>`)
+	tests := []struct {
+		name       string
+		stylesheet string
+		want       string
+	}{
+		{"short id beats long id", "#a { color: red; } p#a { color: green; } #a { color: blue; }", "green"},
+		{"type selectors are counted, not their length", "p { color: green; } div, * { color: red; }", "green"},
+		{"universal selector adds nothing", "*.x { color: red; } .x { color: green; }", "green"},
+		{"source order between equal specificities", ".x { color: red; } .y { color: blue; } .x { color: green; }", "green"},
+		{"most specific matching selector of a list", "#a, p { color: green; } .x.y { color: red; }", "green"},
+		{"stable order with many rules", "p.x { color: red; } .x.y { color: blue; } p { color: red; } .y.x { color: green; } p { color: red; }", "green"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := css.NewParser(strings.NewReader(tt.stylesheet))
+			style := p.ParseStylesheet()
+			if len(p.Errors()) > 0 {
+				t.Fatal(p.Errors())
+			}
+			if got := specifiedValues(node, style)["color"].Keyword; got != tt.want {
+				t.Errorf("color = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_specifiedValues_origins(t *testing.T) {
+	node := htmlParseSnippet(t, `<p id="a" style="color: orange; width: 1px !important">text</p>`)
+	parse := func(stylesheet string, origin css.Origin) *css.Stylesheet {
+		p := css.NewParser(strings.NewReader(stylesheet))
+		s := p.ParseStylesheet()
+		if len(p.Errors()) > 0 {
+			t.Fatal(p.Errors())
+		}
+		s.SetOrigin(origin)
+		return s
+	}
+	style := css.Merge(
+		parse(`p { display: block !important; height: 1px; margin-left: 1px !important; }`, css.UserAgent),
+		parse(`p { height: 2px; width: 2px !important; padding-left: 2px !important; margin-left: 2px !important; }`, css.User),
+		parse(`
+			p { padding-left: 3px; display: inline !important; }
+			#a { height: 3px; width: 3px !important; }
+			p { color: red !important; }
+			#a { color: blue; }
+		`, css.Author),
+	)
+
+	want := PropertyMap{
+		"display":      css.Value{Keyword: "block"},                              // important user-agent
+		"margin-left":  css.Value{Length: css.Length{Quantity: 1, Unit: css.Px}}, // important user-agent over important user
+		"width":        css.Value{Length: css.Length{Quantity: 2, Unit: css.Px}}, // important user over important inline
+		"padding-left": css.Value{Length: css.Length{Quantity: 2, Unit: css.Px}}, // important user over normal author
+		"height":       css.Value{Length: css.Length{Quantity: 3, Unit: css.Px}}, // normal author over normal user
+		"color":        css.Value{Keyword: "red"},                                // important author over inline
+	}
+	if got := specifiedValues(node, style); !reflect.DeepEqual(got, want) {
+		t.Errorf("specifiedValues() = %v, want %v", got, want)
+	}
+
+	node = htmlParseSnippet(t, `<p id="a" style="color: orange">text</p>`)
+	if got := specifiedValues(node, style)["color"]; got != (css.Value{Keyword: "red"}) {
+		t.Errorf("color = %v, want the important author rule", got)
+	}
+	node = htmlParseSnippet(t, `<p id="a" style="color: orange !important">text</p>`)
+	if got := specifiedValues(node, style)["color"]; got != (css.Value{Keyword: "orange"}) {
+		t.Errorf("color = %v, want the important inline style", got)
+	}
+	node = htmlParseSnippet(t, `<p style="height: 4px">text</p>`)
+	if got := specifiedValues(node, style)["height"].Length.Quantity; got != 4 {
+		t.Errorf("height = %v, want the inline style over the author rules", got)
+	}
+}
+
+func TestGenerateStyleTree_text(t *testing.T) {
+	node := htmlParseSnippet(t, `<p class="note">Some text</p>`)
+	style := &css.Stylesheet{
+		Rules: []css.Rule{
+			{
+				Selectors: []css.Selector{
+					{Classes: []string{"note"}},
+				},
+				Declarations: []css.Declaration{
+					{Name: "color", Value: css.Value{Color: css.Color{Name: "red"}}},
+					{Name: "font-size", Value: css.Value{Length: css.Length{Quantity: 12, Unit: css.Px}}},
+					{Name: "width", Value: css.Value{Length: css.Length{Quantity: 900, Unit: css.Px}}},
+				},
+			},
+		},
+	}
+
+	tree := GenerateStyleTree(node, style, css.Device{})
+	if len(tree.Children) != 1 {
+		t.Fatalf("expected 1 child, got %d", len(tree.Children))
+	}
+
+	want := PropertyMap{
+		"color":     css.Value{Color: css.Color{Name: "red"}},
+		"font-size": css.Value{Length: css.Length{Quantity: 12, Unit: css.Px}},
+	}
+	if got := tree.Children[0].ComputedValues; !reflect.DeepEqual(got, want) {
+		t.Errorf("text node values = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateStyleTree_generatedContent(t *testing.T) {
+	node := htmlParseSnippet(t, `<ol>
+		<li title="first">a</li>
+		<li title="second">b<ol><li>c</li><li>d</li></ol></li>
+		<li class="none">e</li>
+	</ol>`)
+	p := css.NewParser(strings.NewReader(`
+		ol { counter-reset: item; }
+		li { color: red; }
+		li::before { counter-increment: item; content: counters(item, ".") " " attr(title); }
+		li::after { content: "!"; }
+		li.none::before { content: none; }
+		li.none::after { content: normal; }
+	`))
+	style := p.ParseStylesheet()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	tree := GenerateStyleTree(node, style, css.Device{})
+
+	var got []string
+	var walk func(n *StyledNode)
+	walk = func(n *StyledNode) {
+		if n.PseudoElement != "" {
+			if len(n.Children) != 1 {
+				t.Fatalf("::%s: expected 1 text child, got %d", n.PseudoElement, len(n.Children))
+			}
+			if n.ComputedValues["color"] != red {
+				t.Errorf("::%s: the color is not inherited from the element", n.PseudoElement)
+			}
+			if n.Display() != Inline {
+				t.Errorf("::%s: expected inline display, got %v", n.PseudoElement, n.Display())
+			}
+			got = append(got, n.PseudoElement+" "+n.Children[0].Node.TextContent)
+			return
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(tree)
+
+	want := []string{
+		"before 1 first", "after !",
+		"before 2 second", "before 2.1 ", "after !", "before 2.2 ", "after !", "after !",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("generated content = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateStyleTree_hiddenCounters(t *testing.T) {
+	node := htmlParseSnippet(t, `<div>
+		<p>a</p>
+		<p class="hidden">b</p>
+		<p>c</p>
+		<section class="hidden"><p>d</p></section>
+		<p class="no-before">e</p>
+		<p>f</p>
+	</div>`)
+	p := css.NewParser(strings.NewReader(`
+		p { counter-increment: n; }
+		p::before { content: counter(n) ". "; }
+		.hidden { display: none; }
+		.no-before::before { display: none; counter-increment: n 10; }
+	`))
+	style := p.ParseStylesheet()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	tree := GenerateStyleTree(node, style, css.Device{})
+
+	// the elements with display: none, and their descendants, neither increment counters nor generate content
+	var got []string
+	var walk func(n *StyledNode)
+	walk = func(n *StyledNode) {
+		if n.PseudoElement != "" {
+			got = append(got, n.Children[0].Node.TextContent)
+			return
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(tree)
+
+	want := []string{"1. ", "2. ", "4. "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("generated content = %q, want %q", got, want)
+	}
+}
+
+func Test_userAgentStylesheet(t *testing.T) {
+	p := css.NewParser(strings.NewReader(userAgentCSS))
+	p.ParseStylesheet()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+
+	root := parser.New(lexer.New(`<html><head><title>T</title><style>b { color: red; }</style></head>
+	<body><h1>Title</h1><p>Some <span>text</span> in <b>bold</b></p><ul><li>item</li></ul><div hidden></div>
+	<table><thead><tr><th>H</th></tr></thead><tbody><tr><td>C</td></tr></tbody><tfoot><tr><td>F</td></tr></tfoot></table></body></html>`)).Parse()
+	author := css.NewParser(strings.NewReader("h1 { margin-top: 0px; }"))
+	tree := GenerateStyleTree(root, author.ParseStylesheet(), css.Device{})
+
+	find := func(tag string) *StyledNode {
+		var found *StyledNode
+		var walk func(n *StyledNode)
+		walk = func(n *StyledNode) {
+			if found == nil && n.Node.Tag == tag {
+				found = n
+			}
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		walk(tree)
+		if found == nil {
+			t.Fatalf("no %s element in the style tree", tag)
+		}
+		return found
+	}
+
+	displays := []struct {
+		tag  string
+		want Display
+	}{
+		{"head", None},
+		{"body", Block},
+		{"h1", Block},
+		{"p", Block},
+		{"span", Inline},
+		{"b", Inline},
+		{"li", Block},
+		{"div", None},
+	}
+	for _, tt := range displays {
+		if got := find(tt.tag).Display(); got != tt.want {
+			t.Errorf("%s: display = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+
+	px := func(quantity float64) css.Value {
+		return css.Value{Length: css.Length{Quantity: quantity, Unit: css.Px}}
+	}
+	values := []struct {
+		tag      string
+		property string
+		want     css.Value
+	}{
+		{"body", "margin-left", px(8)},
+		{"h1", "font-size", px(32)},
+		{"h1", "margin-top", px(0)},
+		{"p", "margin-bottom", px(16)},
+		{"ul", "padding-left", px(40)},
+		{"b", "font-weight", css.Value{Keyword: "bold"}},
+		{"th", "font-weight", css.Value{Keyword: "bold"}},
+		{"thead", "display", css.Value{Keyword: "block"}},
+		{"tbody", "display", css.Value{Keyword: "block"}},
+		{"tfoot", "display", css.Value{Keyword: "block"}},
+		{"tr", "display", css.Value{Keyword: "block"}},
+		{"th", "display", css.Value{Keyword: "block"}},
+		{"td", "display", css.Value{Keyword: "block"}},
+	}
+	for _, tt := range values {
+		if got := find(tt.tag).ComputedValues[tt.property]; got != tt.want {
+			t.Errorf("%s: %s = %v, want %v", tt.tag, tt.property, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentStylesheets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "linked.css"), []byte("p { width: 2px; }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := parser.New(lexer.New(`<head>
+		<style>p { width: 1px; }</style>
+		<link rel="stylesheet" href="linked.css">
+		<link rel="icon" href="favicon.css">
+		<link rel="alternate stylesheet" href="alternate.css">
+	</head>
+	<body><style>p { width: 3px; }</style><p></p></body>`)).Parse()
+
+	sheets, errs := DocumentStylesheets(root, dir)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if len(sheets) != 3 {
+		t.Fatalf("expected 3 stylesheets, got %d", len(sheets))
+	}
+	for i, sheet := range sheets {
+		if len(sheet.Rules) != 1 {
+			t.Fatalf("sheets[%d]: expected 1 rule, got %d", i, len(sheet.Rules))
+		}
+		if got := sheet.Rules[0].Declarations[0].Value.Length.Quantity; got != float64(i+1) {
+			t.Errorf("sheets[%d]: bad order, got width %v", i, got)
+		}
+	}
+
+	_, errs = DocumentStylesheets(root, filepath.Join(dir, "missing"))
+	if len(errs) != 1 {
+		t.Errorf("expected an error for the missing linked stylesheet, got %v", errs)
+	}
+}
+
+func TestGenerateStyleTree_media(t *testing.T) {
+	node := htmlParseSnippet(t, `<div><p></p></div>`)
+	p := css.NewParser(strings.NewReader(`
+		div { width: 100px; font-size: 10px; }
+		@media (min-width: 600px) {
+			div { width: 200px; }
+			p { font-size: 5vw; }
+		}
+		@media print { div { width: 300px; } }
+	`))
+	stylesheet := p.ParseStylesheet()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+
+	tests := []struct {
+		name     string
+		device   css.Device
+		width    float64
+		fontSize float64
+	}{
+		{"narrow", css.Device{Width: 300, Height: 300}, 100, 10},
+		{"wide", css.Device{Width: 800, Height: 600}, 200, 40},
+		{"print", css.Device{Type: "print", Width: 300, Height: 300}, 300, 10},
+	}
+	for _, tt := range tests {
+		tree := GenerateStyleTree(node, stylesheet, tt.device)
+		if got := tree.ComputedValues["width"].Length.Quantity; got != tt.width {
+			t.Errorf("%s: div width = %v, want %v", tt.name, got, tt.width)
+		}
+		// the viewport lengths of font-size are resolved against the device
+		if got := tree.Children[0].ComputedValues["font-size"].Length.Quantity; got != tt.fontSize {
+			t.Errorf("%s: p font-size = %v, want %v", tt.name, got, tt.fontSize)
+		}
+	}
+}
+
+func TestLoadStylesheet_imports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "css"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"main.css": `@import url(css/base.css);
+			@import "css/print.css" print;
+			@import url(css/grid.css) supports(display: grid);
+			@import url(missing.css);
+			p { width: 1px; }`,
+		// imports are relative to the stylesheet importing them, and the cycle back to main.css is skipped
+		"css/base.css":   `@import "../main.css"; @import "colors.css"; p { width: 2px; }`,
+		"css/colors.css": `p { color: red; }`,
+		"css/print.css":  `@media (min-width: 600px) { p { height: 3px; } }`,
+		"css/grid.css":   `p { width: 4px; }`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sheet, errs := LoadStylesheet(filepath.Join(dir, "main.css"))
+	if sheet == nil {
+		t.Fatal(errs)
+	}
+	// the import cycle and the missing file
+	if len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+
+	rules := []struct {
+		color    bool
+		length   float64 // the width, or the height of print.css
+		media    int
+		supports int
+	}{
+		{true, 0, 0, 0},
+		{false, 2, 0, 0},
+		{false, 3, 2, 0},
+		{false, 4, 0, 1},
+		{false, 1, 0, 0},
+	}
+	if len(sheet.Rules) != len(rules) {
+		t.Fatalf("expected %d rules, got %d", len(rules), len(sheet.Rules))
+	}
+	for i, tt := range rules {
+		rule := sheet.Rules[i]
+		d := rule.Declarations[0]
+		if (d.Name == "color") != tt.color || !tt.color && d.Value.Length.Quantity != tt.length {
+			t.Errorf("rules[%d]: bad order, got %v", i, d)
+		}
+		if len(rule.Media) != tt.media || len(rule.Supports) != tt.supports {
+			t.Errorf("rules[%d]: expected %d media and %d supports conditions, got %+v", i, tt.media, tt.supports, rule.Conditions)
+		}
+	}
+
+	node := htmlParseSnippet(t, `<div><p></p></div>`)
+	tests := []struct {
+		name   string
+		device css.Device
+		height float64
+	}{
+		{"screen", css.Device{Width: 800}, 0},
+		{"narrow print", css.Device{Type: "print", Width: 300}, 0},
+		{"wide print", css.Device{Type: "print", Width: 800}, 3},
+	}
+	for _, tt := range tests {
+		tree := GenerateStyleTree(node, sheet, tt.device)
+		if got := tree.Children[0].ComputedValues["height"].Length.Quantity; got != tt.height {
+			t.Errorf("%s: p height = %v, want %v", tt.name, got, tt.height)
+		}
+	}
+}
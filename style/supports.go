@@ -0,0 +1,152 @@
+package style
+
+import (
+	"strings"
+
+	"github.com/lysrt/bro/css"
+)
+
+// supportedProperties are the longhands the engine implements, with the values it supports for them.
+// The shorthands are supported when they expand, see shorthands.
+var supportedProperties = withBoxSides(map[string]func(css.Value) bool{
+	"display":           keywords("block", "inline", "list-item", "none"),
+	"width":             lengthOr("auto"),
+	"height":            lengthOr("auto"),
+	"color":             isColor,
+	"background-color":  isColor,
+	"font-family":       isFontFamily,
+	"font-size":         isFontSize,
+	"font-weight":       isFontWeight,
+	"font-style":        keywords("normal", "italic", "oblique"),
+	"line-height":       isLineHeight,
+	"content":           anyValue,
+	"counter-reset":     anyValue,
+	"counter-increment": anyValue,
+})
+
+// withBoxSides adds the margin, padding and border longhands of the four sides to properties.
+func withBoxSides(properties map[string]func(css.Value) bool) map[string]func(css.Value) bool {
+	for _, side := range boxSides {
+		properties["margin-"+side] = lengthOr("auto")
+		properties["padding-"+side] = lengthOr()
+		properties["border-"+side+"-width"] = lengthOr("thin", "medium", "thick")
+		properties["border-"+side+"-style"] = isBorderStyle
+		properties["border-"+side+"-color"] = isColor
+	}
+	return properties
+}
+
+// supportsDeclaration reports whether the engine implements a declaration, for the tests of @supports
+// like (display: grid). Custom properties, and values using var(), are supported for the known properties.
+func supportsDeclaration(d css.Declaration) bool {
+	if css.IsCustomProperty(d.Name) {
+		return true
+	}
+	name := strings.ToLower(d.Name)
+	supported, longhand := supportedProperties[name]
+	s, shorthand := shorthands[name]
+	switch {
+	case !longhand && !shorthand:
+		return false
+	case d.Value.Raw != nil || isCSSWideKeyword(d.Value):
+		return true
+	case shorthand:
+		_, ok := s.expand(d.Value)
+		return ok
+	}
+	return supported(d.Value)
+}
+
+func keywords(names ...string) func(css.Value) bool {
+	return func(v css.Value) bool {
+		for _, name := range names {
+			if strings.EqualFold(v.Keyword, name) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// lengthOr returns a test accepting lengths, percentages and the keywords.
+func lengthOr(names ...string) func(css.Value) bool {
+	isKeyword := keywords(names...)
+	return func(v css.Value) bool {
+		return isLength(v) || isKeyword(v)
+	}
+}
+
+// isLength reports whether a value is a length, a percentage, or a math function resolving to one.
+func isLength(v css.Value) bool {
+	if v.Math != nil {
+		return !v.Math.IsNumber()
+	}
+	if v != (css.Value{Length: v.Length}) {
+		return false
+	}
+	switch u := v.Length.Unit; {
+	case u == "":
+		// unitless zero
+		return v.Length.Quantity == 0
+	case u == css.Percent || u.Absolute() || u.FontRelative() || u.ViewportRelative():
+		return true
+	}
+	return false
+}
+
+func isNumber(v css.Value) bool {
+	if v.Math != nil {
+		return v.Math.IsNumber()
+	}
+	return v == css.Value{Length: css.Length{Quantity: v.Length.Quantity}}
+}
+
+// isColor accepts the parsed colors, which are never the zero Color: a fully transparent black,
+// like #0000, is parsed as css.Transparent. See isLength for the unitless 0.
+func isColor(v css.Value) bool {
+	if v.Color != (css.Color{}) || strings.EqualFold(v.Keyword, "currentcolor") {
+		return true
+	}
+	_, ok := css.NamedColor(v.Keyword)
+	return ok
+}
+
+func isBorderStyle(v css.Value) bool {
+	return borderStyles[strings.ToLower(v.Keyword)]
+}
+
+// isFontFamily accepts a family, or a comma separated list of families.
+func isFontFamily(v css.Value) bool {
+	families := []css.Value{v}
+	if v.List != nil && v.List.Comma {
+		families = v.List.Values
+	}
+	for _, family := range families {
+		for _, word := range components(family) {
+			if word.Keyword == "" && word.Text == "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isFontSize(v css.Value) bool {
+	_, ok := fontSizeKeywords[strings.ToLower(v.Keyword)]
+	return ok || isLength(v) || keywords("larger", "smaller")(v)
+}
+
+func isFontWeight(v css.Value) bool {
+	if isNumber(v) && v.Math == nil {
+		return v.Length.Quantity >= 1 && v.Length.Quantity <= 1000
+	}
+	return keywords("normal", "bold", "bolder", "lighter")(v)
+}
+
+func isLineHeight(v css.Value) bool {
+	return isNumber(v) || isLength(v) || keywords("normal")(v)
+}
+
+func anyValue(v css.Value) bool {
+	return true
+}
@@ -0,0 +1,96 @@
+package style
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lysrt/bro/css"
+)
+
+func Test_supportsDeclaration(t *testing.T) {
+	tests := []struct {
+		declaration string
+		want        bool
+	}{
+		{"display: block", true},
+		{"display: list-item", true},
+		{"display: grid", false},
+		{"display: flex", false},
+		{"DISPLAY: None", true},
+		{"width: 50%", true},
+		{"width: calc(100% - 2em)", true},
+		{"width: auto", true},
+		{"width: 0", true},
+		{"width: 10", false},
+		{"width: red", false},
+		{"padding-left: auto", false},
+		{"margin-top: auto", true},
+		{"border-top-width: thick", true},
+		{"border-left-style: dashed", true},
+		{"border-left-style: wavy", false},
+		{"color: rebeccapurple", true},
+		{"color: #0af8", true},
+		{"color: #0000", true},
+		{"color: rgba(0, 0, 0, 0)", true},
+		{"color: transparent", true},
+		{"color: 0", false},
+		{"color: currentColor", true},
+		{"color: 12px", false},
+		{"font-family: \"Go Mono\", monospace", true},
+		{"font-size: x-large", true},
+		{"font-size: 1.5rem", true},
+		{"font-weight: 700", true},
+		{"font-weight: 1200", false},
+		{"font-style: oblique", true},
+		{"line-height: 1.5", true},
+		{"line-height: normal", true},
+		{"margin: 4px auto", true},
+		{"margin: 1px 2px 3px 4px 5px", false},
+		{"border: 1px solid red", true},
+		{"font: italic bold 12px/1.5 serif", true},
+		{"float: left", false},
+		{"position: sticky", false},
+		{"width: inherit", true},
+		{"float: inherit", false},
+		{"width: var(--gap)", true},
+		{"--brand-color: #0af", true},
+	}
+	for _, tt := range tests {
+		declarations := css.NewParser(strings.NewReader(tt.declaration)).ParseDeclarations()
+		if len(declarations) != 1 {
+			t.Errorf("%s: expected 1 declaration, got %d", tt.declaration, len(declarations))
+			continue
+		}
+		if got := supportsDeclaration(declarations[0]); got != tt.want {
+			t.Errorf("supportsDeclaration(%s) = %v, want %v", tt.declaration, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateStyleTree_supports(t *testing.T) {
+	node := htmlParseSnippet(t, `<div></div>`)
+	p := css.NewParser(strings.NewReader(`
+		div { width: 1px; }
+		@supports (display: grid) { div { width: 2px; } }
+		@supports (display: block) and (not (float: left)) { div { height: 3px; } }
+		@supports selector(div > p) { div { margin-left: 4px; } }
+	`))
+	tree := GenerateStyleTree(node, p.ParseStylesheet(), css.Device{})
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+
+	tests := []struct {
+		name string
+		want css.Value
+	}{
+		{"width", px(1)},
+		{"height", px(3)},
+		{"margin-left", px(4)},
+	}
+	for _, tt := range tests {
+		if got := tree.ComputedValues[tt.name]; got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package style
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/lysrt/bro/css"
+)
+
+// userAgentCSS is the default stylesheet of the browser, applied below the user and author ones.
+// It is modeled on the rendering section of the HTML standard.
+// See https://html.spec.whatwg.org/multipage/rendering.html
+const userAgentCSS = `
+[hidden], area, base, basefont, datalist, head, link, meta, noembed,
+noframes, param, rp, script, style, template, title {
+	display: none;
+}
+
+html, address, blockquote, body, center, dialog, div, figure, figcaption,
+footer, form, header, hr, legend, listing, main, p, plaintext, pre, xmp,
+article, aside, h1, h2, h3, h4, h5, h6, hgroup, nav, section,
+dir, dd, dl, dt, menu, ol, ul, fieldset, details, summary, table, caption,
+thead, tbody, tfoot, tr, td, th {
+	display: block;
+}
+
+li {
+	display: list-item;
+}
+
+a, abbr, b, bdi, bdo, br, cite, code, data, dfn, em, font, i, img, kbd, label,
+mark, q, s, samp, small, span, strike, strong, sub, sup, time, tt, u, var {
+	display: inline;
+}
+
+body {
+	margin-top: 8px;
+	margin-right: 8px;
+	margin-bottom: 8px;
+	margin-left: 8px;
+}
+
+p, blockquote, figure, listing, plaintext, pre, xmp, dl {
+	margin-top: 1em;
+	margin-bottom: 1em;
+}
+
+blockquote, figure {
+	margin-left: 40px;
+	margin-right: 40px;
+}
+
+dd {
+	margin-left: 40px;
+}
+
+dir, menu, ol, ul {
+	margin-top: 1em;
+	margin-bottom: 1em;
+	padding-left: 40px;
+}
+
+:is(dir, dl, menu, ol, ul) :is(dir, dl, menu, ol, ul) {
+	margin-top: 0px;
+	margin-bottom: 0px;
+}
+
+h1 { font-size: 2em; margin-top: 0.67em; margin-bottom: 0.67em; }
+h2 { font-size: 1.5em; margin-top: 0.83em; margin-bottom: 0.83em; }
+h3 { font-size: 1.17em; margin-top: 1em; margin-bottom: 1em; }
+h4 { font-size: 1em; margin-top: 1.33em; margin-bottom: 1.33em; }
+h5 { font-size: 0.83em; margin-top: 1.67em; margin-bottom: 1.67em; }
+h6 { font-size: 0.67em; margin-top: 2.33em; margin-bottom: 2.33em; }
+
+h1, h2, h3, h4, h5, h6, b, strong, th, dt {
+	font-weight: bold;
+}
+
+i, cite, em, var, dfn, address {
+	font-style: italic;
+}
+
+code, kbd, samp, tt, pre, listing, plaintext, xmp {
+	font-family: monospace;
+}
+
+small {
+	font-size: smaller;
+}
+`
+
+var (
+	userAgentOnce sync.Once
+	userAgent     *css.Stylesheet
+)
+
+// userAgentStylesheet returns the parsed default stylesheet, with the user-agent origin.
+func userAgentStylesheet() *css.Stylesheet {
+	userAgentOnce.Do(func() {
+		userAgent = css.NewParser(strings.NewReader(userAgentCSS)).ParseStylesheet()
+		userAgent.SetOrigin(css.UserAgent)
+	})
+	return userAgent
+}
@@ -0,0 +1,123 @@
+package style
+
+import (
+	"strings"
+
+	"github.com/lysrt/bro/css"
+)
+
+// customProperties resolves the custom properties of a node, substituting the var() of their values.
+type customProperties struct {
+	specified, parent PropertyMap
+	computed          PropertyMap
+
+	resolved map[string]bool
+	// stack holds the properties being resolved, to find the cycles of references
+	stack   []string
+	inCycle map[string]bool
+}
+
+// computeCustomProperties returns the computed values of the custom properties of a node:
+// the ones it specifies, with their var() substituted, over the ones inherited from its parent.
+// Custom properties referring to each other in a cycle are invalid, and are left unset.
+// See https://www.w3.org/TR/css-variables-1/#cycles
+func computeCustomProperties(specified, parent PropertyMap) PropertyMap {
+	c := &customProperties{
+		specified: specified,
+		parent:    parent,
+		computed:  make(PropertyMap),
+		resolved:  make(map[string]bool),
+		inCycle:   make(map[string]bool),
+	}
+	for name, value := range parent {
+		if css.IsCustomProperty(name) {
+			c.computed[name] = value
+		}
+	}
+	for name := range specified {
+		if css.IsCustomProperty(name) {
+			c.resolve(name)
+		}
+	}
+	return c.computed
+}
+
+// resolve computes the value of a custom property, and returns its tokens.
+// It returns false if the property has no valid value.
+func (c *customProperties) resolve(name string) ([]css.RawToken, bool) {
+	for i, n := range c.stack {
+		if n == name {
+			for _, n := range c.stack[i:] {
+				c.inCycle[n] = true
+			}
+			return nil, false
+		}
+	}
+
+	if !c.resolved[name] {
+		c.resolved[name] = true
+		if value, ok := c.specified[name]; ok && value.Raw == nil {
+			// not a raw value: the property is invalid
+			delete(c.computed, name)
+		} else if ok {
+			c.stack = append(c.stack, name)
+			tokens, ok := css.Substitute(value.Raw.Tokens, c.resolve)
+			c.stack = c.stack[:len(c.stack)-1]
+
+			switch {
+			case !ok || c.inCycle[name]:
+				delete(c.computed, name)
+			case isKeyword(tokens, "initial"):
+				delete(c.computed, name)
+			case isKeyword(tokens, "inherit") || isKeyword(tokens, "unset"):
+				// custom properties are inherited, the value of the parent is kept
+			default:
+				c.computed[name] = css.Value{Raw: &css.RawValue{Tokens: tokens}}
+			}
+		}
+	}
+
+	value, ok := c.computed[name]
+	if !ok || value.Raw == nil {
+		return nil, false
+	}
+	return value.Raw.Tokens, true
+}
+
+// isKeyword tells if the tokens of a raw value are a single keyword.
+func isKeyword(tokens []css.RawToken, keyword string) bool {
+	return len(tokens) == 1 && tokens[0].Type == css.IDENTIFIER && strings.EqualFold(tokens[0].Litteral, keyword)
+}
+
+// substituteVariables returns the value of a property using var(), with the custom properties
+// of the node substituted. A value which is invalid once substituted is unset.
+// See https://www.w3.org/TR/css-variables-1/#invalid-at-computed-value-time
+func substituteVariables(name string, value css.Value, custom PropertyMap) css.Value {
+	unset := css.Value{Keyword: "unset"}
+	if value.Raw == nil {
+		return unset
+	}
+	tokens, ok := css.Substitute(value.Raw.Tokens, func(name string) ([]css.RawToken, bool) {
+		value, ok := custom[name]
+		if !ok || value.Raw == nil {
+			return nil, false
+		}
+		return value.Raw.Tokens, true
+	})
+	if !ok {
+		return unset
+	}
+	substituted, ok := css.ParseValue(tokens)
+	if !ok {
+		return unset
+	}
+
+	if value.Raw.Shorthand != "" {
+		// the shorthand is expanded now, and the value of this longhand picked
+		substituted, ok = expandLonghand(value.Raw.Shorthand, name, substituted)
+		if !ok {
+			return unset
+		}
+	}
+	return substituted
+}
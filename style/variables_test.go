@@ -0,0 +1,104 @@
+package style
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lysrt/bro/css"
+)
+
+func TestGenerateStyleTree_variables(t *testing.T) {
+	node := htmlParseSnippet(t, `<div><p><span></span></p></div>`)
+	p := css.NewParser(strings.NewReader(`
+		div {
+			--brand: #0af;
+			--gap: 4px 8px;
+			--a: var(--b);
+			--b: var(--a, 1px);
+			--c: var(--a, 2px);
+			--d: initial;
+		}
+		p {
+			color: var(--brand);
+			margin: var(--gap);
+			width: var(--missing, calc(var(--c) * 5));
+			height: var(--a, 3px);
+			padding-left: var(--missing);
+		}
+		span {
+			--brand: inherit;
+			color: var(--d, red);
+		}
+	`))
+	tree := GenerateStyleTree(node, p.ParseStylesheet(), css.Device{})
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatal(errors)
+	}
+	paragraph := tree.Children[0]
+	span := paragraph.Children[0]
+
+	// the custom properties in a cycle are invalid, even with a fallback
+	for _, name := range []string{"--a", "--b", "--d"} {
+		if value, ok := tree.ComputedValues[name]; ok {
+			t.Errorf("%s = %v, want no value", name, value)
+		}
+	}
+	if c := tree.ComputedValues["--c"]; c.Raw == nil || len(c.Raw.Tokens) != 2 || c.Raw.Tokens[0].Litteral != "2" {
+		t.Errorf("--c = %v, want 2px", c)
+	}
+
+	brand := css.Value{Color: css.Color{A: 255, R: 0, G: 0xaa, B: 0xff}}
+	want := PropertyMap{
+		"color":         brand,
+		"margin-top":    px(4),
+		"margin-right":  px(8),
+		"margin-bottom": px(4),
+		"margin-left":   px(8),
+		"width":         px(10),
+		"height":        px(3),
+		"display":       {Keyword: "block"},
+	}
+	for name, value := range paragraph.ComputedValues {
+		if css.IsCustomProperty(name) {
+			// inherited from div
+			if !reflect.DeepEqual(value, tree.ComputedValues[name]) {
+				t.Errorf("p: %s = %v, want the value of div", name, value)
+			}
+			delete(paragraph.ComputedValues, name)
+		}
+	}
+	if !reflect.DeepEqual(paragraph.ComputedValues, want) {
+		t.Errorf("p: computed values = %v, want %v", paragraph.ComputedValues, want)
+	}
+
+	if got := span.ComputedValues["color"]; got != red {
+		t.Errorf("span: color = %v, want %v", got, red)
+	}
+	if !reflect.DeepEqual(span.ComputedValues["--brand"], tree.ComputedValues["--brand"]) {
+		t.Errorf("span: --brand = %v, want the value of div", span.ComputedValues["--brand"])
+	}
+
+	// a custom property without value is dropped, like `--brand;` or style="--brand"
+	node = htmlParseSnippet(t, `<div><p style="--gap"></p></div>`)
+	p = css.NewParser(strings.NewReader(`div { --brand; color: var(--brand, red); }`))
+	tree = GenerateStyleTree(node, p.ParseStylesheet(), css.Device{})
+	if len(p.Errors()) != 1 {
+		t.Errorf("expected an error for --brand, got %v", p.Errors())
+	}
+	if got := tree.ComputedValues["color"]; got != red {
+		t.Errorf("div: color = %v, want %v", got, red)
+	}
+	if _, ok := tree.Children[0].ComputedValues["--gap"]; ok {
+		t.Errorf("p: --gap should have no value")
+	}
+
+	// a custom property which is not a raw value is invalid
+	custom := computeCustomProperties(PropertyMap{"--brand": {Keyword: "blue"}}, nil)
+	if _, ok := custom["--brand"]; ok {
+		t.Errorf("--brand should have no value, got %v", custom["--brand"])
+	}
+	if got := substituteVariables("width", css.Value{}, custom); got.Keyword != "unset" {
+		t.Errorf("substituteVariables() = %v, want unset", got)
+	}
+}